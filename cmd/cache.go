@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GuLuGuLuGit/review-go/internal/cache"
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+)
+
+// defaultCacheSizeMB 是无法读取配置时使用的缓存大小上限，与 config 中的默认值一致。
+const defaultCacheSizeMB = 50
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "管理 LLM 回复缓存",
+	Long: `管理保存在 .git/review-go/cache 中的 LLM 回复缓存。
+
+缓存 key 由提供商、模型、提示词版本与规范化后的 diff 组成，
+对未变化的暂存区重复运行时直接复用之前的审查结果，不再重复调用 LLM。`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "查看缓存的条目数与占用空间",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := cache.Open(cacheMaxSize())
+		if err != nil {
+			return fmt.Errorf("打开缓存失败: %w", err)
+		}
+		s, err := c.Stats()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "目录：%s\n", s.Dir)
+		fmt.Fprintf(out, "条目：%d\n", s.Entries)
+		limit := "不限"
+		if s.MaxSize > 0 {
+			limit = formatBytes(s.MaxSize)
+		}
+		fmt.Fprintf(out, "大小：%s / %s\n", formatBytes(s.Size), limit)
+		if s.Entries > 0 {
+			fmt.Fprintf(out, "最早使用：%s\n", s.Oldest.Local().Format("2006-01-02 15:04"))
+			fmt.Fprintf(out, "最近使用：%s\n", s.Newest.Local().Format("2006-01-02 15:04"))
+		}
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "清空缓存",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := cache.Open(cacheMaxSize())
+		if err != nil {
+			return fmt.Errorf("打开缓存失败: %w", err)
+		}
+		n, err := c.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "已删除 %d 个缓存条目。\n", n)
+		return nil
+	},
+}
+
+// cacheMaxSize 返回配置中的缓存大小上限（字节），无法读取配置时使用默认值。
+func cacheMaxSize() int64 {
+	mb := defaultCacheSizeMB
+	if cfg, err := config.Load(); err == nil {
+		mb = cfg.Cache.MaxSizeMB
+	}
+	return int64(mb) << 20
+}
+
+// formatBytes 把字节数格式化为便于阅读的形式。
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+	"github.com/GuLuGuLuGit/review-go/internal/usage"
+)
+
+var commitMsgCmd = &cobra.Command{
+	Use:   "commit-msg",
+	Short: "根据暂存区的全部变更生成提交信息",
+	Long: `根据暂存区中的全部变更（不限于 .go 文件）生成提交信息，输出到标准输出。
+
+默认遵循 Conventional Commits 规范，语言与风格可以在配置文件的 commit 中设置，
+也可以用 --language / --style 临时指定。请求同样经过外发策略、密钥脱敏、审计日志与预算检查；
+外发策略不允许发送的文件不包含在请求中。
+
+也可以作为 prepare-commit-msg 钩子使用（review-go hook install prepare-commit-msg），
+或在 TUI 中按 C 生成后编辑并提交。`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := newRunner(cmd, "", "")
+		if err != nil {
+			return err
+		}
+
+		draft, err := runner.CommitMessage()
+		if err != nil {
+			return err
+		}
+
+		reportDraft(cmd, runner, draft)
+		fmt.Fprintln(cmd.OutOrStdout(), draft.Message)
+		return nil
+	},
+}
+
+// reportDraft 把未发送的文件与本次用量写到标准错误，不混入标准输出中生成的内容。
+func reportDraft(cmd *cobra.Command, runner *review.Runner, draft *review.Draft) {
+	errOut := cmd.ErrOrStderr()
+	if len(draft.Denied) > 0 {
+		fmt.Fprintf(errOut, "按外发策略未发送 %d 个文件：%s\n", len(draft.Denied), strings.Join(draft.Denied, "、"))
+	}
+	if totals, ok := runner.Usage(); ok && draft.Usage.Total() > 0 {
+		label := "用量"
+		if runner.DryRun() {
+			label = "估算用量"
+		}
+		fmt.Fprintf(errOut, "%s：%s\n", label, usage.Format(draft.Usage, totals.Currency, totals.Priced))
+	}
+}
+
+// prepareCommitMsg 实现 prepare-commit-msg 钩子：args 为 git 传入的 <提交信息文件> [<来源> [<提交>]]。
+//
+// 只在来源为空（没有通过 -m、-F、模板、合并或 --amend 等方式提供提交信息）时生成，
+// 生成的内容写在文件开头，保留 git 追加的注释。生成失败只给出提示，不阻止提交。
+func prepareCommitMsg(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("prepare-commit-msg 钩子缺少提交信息文件参数")
+	}
+	if len(args) > 1 && args[1] != "" {
+		return nil
+	}
+
+	errOut := cmd.ErrOrStderr()
+	runner, err := newRunner(cmd, "", "")
+	if err != nil {
+		fmt.Fprintf(errOut, "review-go：未生成提交信息：%v\n", err)
+		return nil
+	}
+	draft, err := runner.CommitMessage()
+	if err != nil {
+		fmt.Fprintf(errOut, "review-go：未生成提交信息：%v\n", err)
+		return nil
+	}
+	reportDraft(cmd, runner, draft)
+
+	path := args[0]
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取提交信息文件失败: %w", err)
+	}
+	content := draft.Message + "\n\n" + strings.TrimLeft(string(existing), "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("写入提交信息文件失败: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	commitMsgCmd.Flags().String("language", "", "提交信息使用的语言，如 English、中文（默认取配置中的 commit.language）")
+	commitMsgCmd.Flags().String("style", "", "提交信息风格：conventional / plain（默认取配置中的 commit.style）")
+	commitMsgCmd.Flags().Bool("dry-run", false, "试运行：不调用 LLM，输出将要发送的确切提示词与估算的 token 数")
+	rootCmd.AddCommand(commitMsgCmd)
+}
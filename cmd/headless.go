@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GuLuGuLuGit/review-go/internal/policy"
+	"github.com/GuLuGuLuGit/review-go/internal/redact"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+	"github.com/GuLuGuLuGit/review-go/internal/usage"
+)
+
+// runHeadless 不启动 TUI，审查暂存区（或 runner 指定的提交范围）中的全部文件，把 Markdown 报告写到 out。
+//
+// 变更涉及多个文件时，报告开头是对全部变更的整体审查（见 review.Runner.ReviewOverall）。
+// 文件按风险评分（见 review.Runner.Triage）从高到低审查，设置了 --max-files 时只审查评分最高的几个文件；
+// 指定了 --group 时同一个包（模块）中的文件合在一起审查。
+// 报告末尾给出汇总：审查的文件数、问题数、整体风险与本次运行的 LLM 用量，以及按评分跳过的文件。
+// 有文件审查或整体审查失败（包括因达到预算上限或发现密钥而没有发送请求）时返回错误，使进程以非零状态退出；
+// 外发策略不允许发送的文件只在报告中列出，不视为失败。
+// failOn 非空时，存在不低于该严重程度、且未被标记为已忽略或不修复的问题也返回错误。
+func runHeadless(out io.Writer, runner *review.Runner, failOn review.Severity) error {
+	base, head := runner.Range()
+	files, err := runner.ChangedFiles()
+	if err != nil {
+		return fmt.Errorf("获取变更文件失败: %w", err)
+	}
+	depUnits := runner.DepsUnits()
+	if len(files) == 0 && len(depUnits) == 0 {
+		if head != "" {
+			fmt.Fprintf(out, "%s..%s 之间没有 .go 文件的变更。\n", shortHash(base), shortHash(head))
+		} else {
+			fmt.Fprintln(out, "暂存区中没有 .go 文件的变更。")
+		}
+		return nil
+	}
+
+	// 按风险评分从高到低审查，设置了 --max-files 时只审查评分最高的几个文件
+	triage := runner.Triage(files)
+	files = triage.Files
+
+	diags := runner.Analyze(files)
+
+	// 按 --group 把文件划分为审查单元；默认每个文件是一个单元。go.mod / go.sum 有变化的模块排在最前面
+	units := append(depUnits, runner.Units(files)...)
+	keys := make([]string, 0, len(units))
+	label := "文件"
+	if runner.Grouped() {
+		label = "审查单元"
+	}
+
+	var (
+		reviews   []*review.FileReview
+		failed    = make(map[string]error)
+		denied    = make(map[string]error)
+		findings  int
+		blocking  int
+		budget    int
+		completed int
+	)
+	for _, u := range units {
+		keys = append(keys, u.Key)
+		rev, err := runner.ReviewUnit(u, diags, false)
+		// LLM 请求失败时仍可能带回不依赖 LLM 的结果（例如依赖检查），照常输出并计入问题数
+		if rev != nil {
+			reviews = append(reviews, rev)
+			findings += len(rev.Findings)
+			blocking += countBlocking(rev.Findings, failOn)
+		}
+		switch {
+		case errors.Is(err, policy.ErrDenied):
+			denied[u.Key] = err
+		case err != nil:
+			failed[u.Key] = err
+			if errors.Is(err, usage.ErrBudgetExceeded) {
+				budget++
+			}
+		case rev != nil:
+			completed++
+		}
+	}
+
+	// 变更涉及多个文件时再整体审查一次，跨文件的问题同样计入问题数与 --fail-on
+	var (
+		overall    *review.FileReview
+		overallErr error
+	)
+	if runner.WantsOverall(keys) {
+		byFile := make(map[string]*review.FileReview, len(reviews))
+		for _, rev := range reviews {
+			byFile[rev.File] = rev
+		}
+		if overall, overallErr = runner.ReviewOverall(byFile, false); overallErr == nil {
+			findings += len(overall.Findings)
+			blocking += countBlocking(overall.Findings, failOn)
+		}
+	}
+
+	// 按 --max-files 跳过的文件不发送给 LLM，但其中导出 API 的不兼容变化照常报告，并计入问题数与 --fail-on
+	skippedAPI := runner.SkippedReviews(triage.Skipped)
+	for _, rev := range skippedAPI {
+		reviews = append(reviews, rev)
+		findings += len(rev.Findings)
+		blocking += countBlocking(rev.Findings, failOn)
+	}
+
+	totals, metered := runner.Usage()
+	usageLabel := "用量"
+	if runner.DryRun() {
+		usageLabel = "估算用量"
+	}
+	printed := reviews
+	if overall != nil {
+		printed = append([]*review.FileReview{overall}, reviews...)
+	}
+	for _, rev := range printed {
+		if rev.File == review.OverallFile {
+			fmt.Fprint(out, "# 整体审查\n\n")
+		} else {
+			fmt.Fprintf(out, "# %s\n\n", rev.File)
+			if len(rev.Files) > 0 {
+				fmt.Fprintf(out, "_包含文件：%s_\n\n", strings.Join(rev.Files, "、"))
+			}
+			if score, ok := triage.Scores[rev.File]; ok {
+				fmt.Fprintf(out, "_风险评分：%s_\n\n", score)
+			}
+		}
+		if len(rev.Redactions) > 0 {
+			fmt.Fprintf(out, "_发送前已脱敏 %d 处：%s_\n\n", len(rev.Redactions), redact.Summary(rev.Redactions))
+		}
+		fmt.Fprintf(out, "%s\n", strings.TrimSpace(rev.Markdown))
+		if md := review.FindingsMarkdown(rev.File, rev.Findings, -1); md != "" {
+			fmt.Fprintf(out, "\n%s", md)
+		}
+		switch {
+		case rev.Cached:
+			fmt.Fprint(out, "\n_结果来自缓存。_\n")
+		case metered && rev.Usage.Total() > 0:
+			fmt.Fprintf(out, "\n_%s：%s_\n", usageLabel, usage.Format(rev.Usage, totals.Currency, totals.Priced))
+		}
+		fmt.Fprint(out, "\n---\n\n")
+	}
+
+	fmt.Fprint(out, "# 汇总\n\n")
+	if head != "" {
+		fmt.Fprintf(out, "- 范围：%s..%s\n", shortHash(base), shortHash(head))
+	}
+	fmt.Fprintf(out, "- %s：%d 个，审查完成 %d 个，失败 %d 个，按外发策略未发送 %d 个\n", label, len(keys), completed, len(failed), len(denied))
+	if len(triage.Skipped) > 0 {
+		fmt.Fprintf(out, "- 按风险评分跳过：%d 个，其中 %d 个只报告了导出 API 的不兼容变化\n", len(triage.Skipped), len(skippedAPI))
+	}
+	fmt.Fprintf(out, "- 问题：%d 个\n", findings)
+	switch {
+	case overall != nil && overall.Risk != "":
+		fmt.Fprintf(out, "- 整体风险：%s\n", overall.Risk)
+	case overallErr != nil:
+		fmt.Fprintf(out, "- 整体审查失败：%v\n", overallErr)
+	}
+	if failOn != "" {
+		fmt.Fprintf(out, "- 不低于 %s 的问题：%d 个\n", failOn, blocking)
+	}
+	if metered {
+		fmt.Fprintf(out, "- %s：%d 次请求，%s\n", usageLabel, totals.Requests, totals)
+		if totals.Warning != "" {
+			fmt.Fprintf(out, "- 警告：%s\n", totals.Warning)
+		}
+	}
+	for _, f := range triage.Skipped {
+		fmt.Fprintf(out, "- 跳过 %s：风险评分 %s\n", f, triage.Scores[f])
+	}
+	for _, f := range keys {
+		if err := denied[f]; err != nil {
+			fmt.Fprintf(out, "- 未发送 %s：%v\n", f, err)
+		}
+		if err := failed[f]; err != nil {
+			fmt.Fprintf(out, "- 审查失败 %s：%v\n", f, err)
+		}
+	}
+
+	switch {
+	case budget > 0:
+		return fmt.Errorf("%d 个%s审查失败，其中 %d 个因达到预算上限未发送请求", len(failed), label, budget)
+	case len(failed) > 0:
+		return fmt.Errorf("%d 个%s审查失败", len(failed), label)
+	case overallErr != nil:
+		return overallErr
+	case blocking > 0:
+		return fmt.Errorf("发现 %d 个严重程度不低于 %s 的问题", blocking, failOn)
+	}
+	return nil
+}
+
+// countBlocking 统计严重程度不低于 failOn 的问题数；failOn 为空时返回 0。
+// 已被标记为已忽略或不修复的问题不计入。
+func countBlocking(findings []review.Finding, failOn review.Severity) int {
+	if failOn == "" {
+		return 0
+	}
+
+	n := 0
+	for _, f := range findings {
+		if f.Decision != nil && (f.Decision.Status == review.DecisionDismissed || f.Decision.Status == review.DecisionWontFix) {
+			continue
+		}
+		if f.Severity.Rank() >= failOn.Rank() {
+			n++
+		}
+	}
+	return n
+}
+
+// parseFailOn 读取 --fail-on 参数。为空或为 none 时返回空字符串，表示不按问题的严重程度判定失败。
+func parseFailOn(cmd *cobra.Command) (review.Severity, error) {
+	s, _ := cmd.Flags().GetString("fail-on")
+	switch sev := review.Severity(strings.ToLower(strings.TrimSpace(s))); sev {
+	case "", "none":
+		return "", nil
+	case review.SeverityHigh, review.SeverityMedium, review.SeverityLow, review.SeverityInfo:
+		return sev, nil
+	default:
+		return "", fmt.Errorf("未知的严重程度 %q（可选 high / medium / low / info / none）", s)
+	}
+}
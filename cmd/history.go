@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+	"github.com/GuLuGuLuGit/review-go/internal/history"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+	"github.com/GuLuGuLuGit/review-go/internal/usage"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "查看本地保存的审查历史",
+	Long: `查看保存在 .git/review-go/history.db 中的审查历史。
+
+每次运行 review-go 都会记录提供商、模型、提示词版本、每个文件的 diff 哈希、
+审查报告与问题列表，可以用来回看针对某个提交的审查，或把审查结果发给同事。`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出最近的审查记录",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		store, err := history.Open()
+		if err != nil {
+			return fmt.Errorf("打开审查历史失败: %w", err)
+		}
+		runs, err := store.List(limit)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if len(runs) == 0 {
+			fmt.Fprintln(out, "暂无审查记录。")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t时间\t分支\tHEAD\t模型\t文件\t问题\tTokens")
+		for _, run := range runs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s/%s\t%d\t%d\t%d\n",
+				run.ID,
+				run.StartedAt.Local().Format("2006-01-02 15:04"),
+				run.Branch,
+				shortHash(run.Head),
+				run.Provider, run.Model,
+				len(run.Files),
+				run.FindingCount(),
+				run.Usage().Total(),
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id> [file]",
+	Short: "以 Markdown 输出某次审查的完整结果",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := history.Open()
+		if err != nil {
+			return fmt.Errorf("打开审查历史失败: %w", err)
+		}
+		run, err := getRun(store, args[0])
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "# 审查记录 #%d\n\n", run.ID)
+		fmt.Fprintf(out, "- 时间：%s\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(out, "- 分支：%s（HEAD %s）\n", run.Branch, shortHash(run.Head))
+		fmt.Fprintf(out, "- 模型：%s / %s（提示词版本 %s）\n", run.Provider, run.Model, run.PromptVersion)
+		if u := run.Usage(); u.Total() > 0 {
+			fmt.Fprintf(out, "- 用量：%s\n", usage.Format(u, usageCurrency(), u.Cost > 0))
+		}
+
+		found := false
+		for _, f := range run.Files {
+			if len(args) == 2 && f.File != args[1] {
+				continue
+			}
+			found = true
+			fmt.Fprintf(out, "\n---\n\n# %s\n\n%s\n", f.File, strings.TrimSpace(f.Markdown))
+			if md := review.FindingsMarkdown(f.File, f.Findings, -1); md != "" {
+				fmt.Fprintf(out, "\n%s", md)
+			}
+		}
+		if len(args) == 2 && !found {
+			return fmt.Errorf("审查记录 #%d 中没有文件 %s", run.ID, args[1])
+		}
+		return nil
+	},
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <file> [<旧 id> <新 id>]",
+	Short: "比较同一文件的两次审查",
+	Long: `比较同一文件的两次审查：代码是否变化、问题的增减以及审查报告的逐行差异。
+
+不指定 id 时比较包含该文件的最近两次审查。`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 3 {
+			return errors.New("用法: review-go history diff <file> [<旧 id> <新 id>]")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := args[0]
+
+		store, err := history.Open()
+		if err != nil {
+			return fmt.Errorf("打开审查历史失败: %w", err)
+		}
+
+		var oldRun, newRun *history.Run
+		if len(args) == 3 {
+			if oldRun, err = getRun(store, args[1]); err != nil {
+				return err
+			}
+			if newRun, err = getRun(store, args[2]); err != nil {
+				return err
+			}
+		} else {
+			runs, err := store.RunsWithFile(file, 2)
+			if err != nil {
+				return err
+			}
+			if len(runs) < 2 {
+				return fmt.Errorf("文件 %s 的审查记录少于两次，无法比较", file)
+			}
+			newRun, oldRun = &runs[0], &runs[1]
+		}
+
+		oldRec, ok := oldRun.File(file)
+		if !ok {
+			return fmt.Errorf("审查记录 #%d 中没有文件 %s", oldRun.ID, file)
+		}
+		newRec, ok := newRun.File(file)
+		if !ok {
+			return fmt.Errorf("审查记录 #%d 中没有文件 %s", newRun.ID, file)
+		}
+
+		printReviewDiff(cmd.OutOrStdout(), file, oldRun, newRun, oldRec, newRec)
+		return nil
+	},
+}
+
+// printReviewDiff 输出同一文件两次审查的对比。
+func printReviewDiff(out io.Writer, file string, oldRun, newRun *history.Run, oldRec, newRec history.FileRecord) {
+	fmt.Fprintf(out, "# %s：#%d → #%d\n\n", file, oldRun.ID, newRun.ID)
+	fmt.Fprintf(out, "- #%d：%s，%s / %s，提示词版本 %s\n", oldRun.ID, oldRec.ReviewedAt.Local().Format("2006-01-02 15:04"), oldRun.Provider, oldRun.Model, oldRun.PromptVersion)
+	fmt.Fprintf(out, "- #%d：%s，%s / %s，提示词版本 %s\n", newRun.ID, newRec.ReviewedAt.Local().Format("2006-01-02 15:04"), newRun.Provider, newRun.Model, newRun.PromptVersion)
+	if oldRec.DiffHash == newRec.DiffHash {
+		fmt.Fprintln(out, "- 代码：两次审查针对的是同一份 diff")
+	} else {
+		fmt.Fprintln(out, "- 代码：两次审查之间 diff 发生了变化")
+	}
+
+	d := history.CompareFindings(oldRec.Findings, newRec.Findings)
+	fmt.Fprintf(out, "\n## 问题（保留 %d，新增 %d，消失 %d）\n\n", len(d.Both), len(d.OnlyNew), len(d.OnlyOld))
+	for _, f := range d.OnlyNew {
+		fmt.Fprintf(out, "+ [%s] %s L%d %s\n", f.Severity, f.Source, f.Line, f.Title)
+	}
+	for _, f := range d.OnlyOld {
+		fmt.Fprintf(out, "- [%s] %s L%d %s\n", f.Severity, f.Source, f.Line, f.Title)
+	}
+	for _, f := range d.Both {
+		fmt.Fprintf(out, "  [%s] %s L%d %s\n", f.Severity, f.Source, f.Line, f.Title)
+	}
+
+	fmt.Fprint(out, "\n## 审查报告差异\n\n")
+	for _, line := range history.DiffLines(oldRec.Markdown, newRec.Markdown) {
+		fmt.Fprintln(out, line)
+	}
+}
+
+// getRun 按命令行参数中的编号读取运行记录。
+func getRun(store *history.Store, arg string) (*history.Run, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(arg, "#"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的审查记录编号 %q", arg)
+	}
+
+	run, err := store.Get(id)
+	if errors.Is(err, history.ErrNotFound) {
+		return nil, fmt.Errorf("审查记录 #%d 不存在", id)
+	}
+	return run, err
+}
+
+// usageCurrency 返回配置中的货币符号，无法读取配置时使用 "$"。
+func usageCurrency() string {
+	if cfg, err := config.Load(); err == nil && cfg.Usage.Currency != "" {
+		return cfg.Usage.Currency
+	}
+	return "$"
+}
+
+// shortHash 返回提交哈希的前 8 位，没有提交时返回 "-"。
+func shortHash(hash string) string {
+	switch {
+	case hash == "":
+		return "-"
+	case len(hash) > 8:
+		return hash[:8]
+	default:
+		return hash
+	}
+}
+
+func init() {
+	historyListCmd.Flags().Int("limit", 20, "最多显示的记录数，0 表示全部")
+
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyDiffCmd)
+	rootCmd.AddCommand(historyCmd)
+}
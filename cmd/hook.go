@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// hookMarker 出现在 review-go 生成的钩子脚本中，用于区分由 review-go 管理的钩子与用户自己的钩子。
+const hookMarker = "# review-go managed hook"
+
+// chainedSuffix 是安装时原有钩子被重命名后的后缀；review-go 的钩子会先执行它。
+const chainedSuffix = ".pre-review-go"
+
+// skipEnv 设置为 1 时钩子跳过 review-go 的审查与提交信息生成（原有的钩子照常执行）。
+const skipEnv = "REVIEW_GO_SKIP"
+
+// supportedHooks 是 review-go 可以安装的钩子；defaultHooks 是不指定钩子名时安装的钩子。
+var (
+	supportedHooks = []string{"pre-commit", "pre-push", "prepare-commit-msg"}
+	defaultHooks   = []string{"pre-commit", "pre-push"}
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "管理运行 review-go 的 Git 钩子",
+	Long: `安装或卸载由 review-go 管理的 pre-commit、pre-push 与 prepare-commit-msg 钩子。
+
+pre-commit 与 pre-push 以无头模式审查：pre-commit 审查暂存区，pre-push 审查将要推送的提交。
+存在不低于 --fail-on 的问题或有文件审查失败时，钩子以非零状态退出，阻止本次提交或推送。
+prepare-commit-msg 根据暂存区生成提交信息（见 review-go commit-msg），需要显式指定才会安装。
+设置环境变量 REVIEW_GO_SKIP=1 可以临时跳过审查与生成。
+
+已有的钩子不会被覆盖：安装时重命名为 <钩子名>.pre-review-go，
+并在 review-go 审查之前执行；卸载时恢复。`,
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install [pre-commit|pre-push|prepare-commit-msg]...",
+	Short: "安装钩子（默认安装 pre-commit 与 pre-push）",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hooks, err := selectHooks(args, defaultHooks)
+		if err != nil {
+			return err
+		}
+		failOn, err := parseFailOn(cmd)
+		if err != nil {
+			return err
+		}
+		if failOn == "" {
+			failOn = "none"
+		}
+
+		dir, err := gitops.GetHooksDir()
+		if err != nil {
+			return fmt.Errorf("获取 hooks 目录失败: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("创建 hooks 目录失败: %w", err)
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			exe = "review-go"
+		}
+
+		out := cmd.OutOrStdout()
+		for _, name := range hooks {
+			path := filepath.Join(dir, name)
+			managed, exists, err := readHook(path)
+			if err != nil {
+				return err
+			}
+
+			// 用户自己的钩子重命名后由 review-go 的钩子链式调用
+			if exists && !managed {
+				chained := path + chainedSuffix
+				if _, err := os.Stat(chained); err == nil {
+					return fmt.Errorf("%s 已存在，无法保存原有的 %s 钩子，请先手动处理", chained, name)
+				}
+				if err := os.Rename(path, chained); err != nil {
+					return fmt.Errorf("重命名原有的 %s 钩子失败: %w", name, err)
+				}
+				fmt.Fprintf(out, "原有的 %s 钩子已重命名为 %s，将在审查之前执行\n", name, filepath.Base(chained))
+			}
+
+			if err := os.WriteFile(path, []byte(hookScript(exe, name, string(failOn))), 0o755); err != nil {
+				return fmt.Errorf("写入 %s 钩子失败: %w", name, err)
+			}
+			fmt.Fprintf(out, "已安装 %s 钩子：%s\n", name, path)
+		}
+		return nil
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall [pre-commit|pre-push|prepare-commit-msg]...",
+	Short: "卸载钩子并恢复原有的钩子（默认卸载全部）",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hooks, err := selectHooks(args, supportedHooks)
+		if err != nil {
+			return err
+		}
+
+		dir, err := gitops.GetHooksDir()
+		if err != nil {
+			return fmt.Errorf("获取 hooks 目录失败: %w", err)
+		}
+
+		out := cmd.OutOrStdout()
+		for _, name := range hooks {
+			path := filepath.Join(dir, name)
+			managed, exists, err := readHook(path)
+			if err != nil {
+				return err
+			}
+			switch {
+			case !exists:
+				fmt.Fprintf(out, "%s 钩子未安装\n", name)
+				continue
+			case !managed:
+				fmt.Fprintf(out, "%s 钩子不是由 review-go 管理的，已跳过\n", name)
+				continue
+			}
+
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("删除 %s 钩子失败: %w", name, err)
+			}
+			chained := path + chainedSuffix
+			if _, err := os.Stat(chained); err == nil {
+				if err := os.Rename(chained, path); err != nil {
+					return fmt.Errorf("恢复原有的 %s 钩子失败: %w", name, err)
+				}
+				fmt.Fprintf(out, "已卸载 %s 钩子，并恢复了原有的钩子\n", name)
+				continue
+			}
+			fmt.Fprintf(out, "已卸载 %s 钩子\n", name)
+		}
+		return nil
+	},
+}
+
+// hookRunCmd 由钩子脚本调用，用户不需要直接执行。
+var hookRunCmd = &cobra.Command{
+	Use:          "run <hook> [-- args...]",
+	Short:        "执行钩子（由钩子脚本调用）",
+	Hidden:       true,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if !slices.Contains(supportedHooks, name) {
+			return fmt.Errorf("不支持的钩子 %q", name)
+		}
+		failOn, err := parseFailOn(cmd)
+		if err != nil {
+			return err
+		}
+
+		// pre-push 从标准输入读取将要推送的引用，原有的钩子也需要同样的输入
+		var input []byte
+		if name == "pre-push" {
+			if input, err = io.ReadAll(cmd.InOrStdin()); err != nil {
+				return fmt.Errorf("读取推送信息失败: %w", err)
+			}
+		}
+
+		if err := runChainedHook(cmd, name, args[1:], input); err != nil {
+			return err
+		}
+
+		if os.Getenv(skipEnv) == "1" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s=1，跳过 review-go\n", skipEnv)
+			return nil
+		}
+
+		switch name {
+		case "prepare-commit-msg":
+			return prepareCommitMsg(cmd, args[1:])
+		case "pre-commit":
+			runner, err := newRunner(cmd, "", "")
+			if err != nil {
+				return err
+			}
+			return runHeadless(cmd.OutOrStdout(), runner, failOn)
+		}
+
+		ranges, err := pushRanges(string(input))
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, rg := range ranges {
+			runner, err := newRunner(cmd, rg.base, rg.head)
+			if err != nil {
+				return err
+			}
+			if err := runHeadless(cmd.OutOrStdout(), runner, failOn); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", rg.ref, err))
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// selectHooks 校验命令行中指定的钩子名，未指定时返回 defaults。
+func selectHooks(args, defaults []string) ([]string, error) {
+	if len(args) == 0 {
+		return defaults, nil
+	}
+	for _, a := range args {
+		if !slices.Contains(supportedHooks, a) {
+			return nil, fmt.Errorf("不支持的钩子 %q（可选 %s）", a, strings.Join(supportedHooks, " / "))
+		}
+	}
+	return args, nil
+}
+
+// readHook 报告 path 处的钩子是否存在，以及是否由 review-go 管理。
+func readHook(path string) (managed, exists bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("读取钩子 %s 失败: %w", path, err)
+	}
+	return bytes.Contains(data, []byte(hookMarker)), true, nil
+}
+
+// hookScript 返回调用 exe 执行 name 钩子的脚本。
+func hookScript(exe, name, failOn string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString(hookMarker + "\n")
+	b.WriteString("# 由 `review-go hook install` 生成，请勿手动修改；卸载请执行 `review-go hook uninstall`。\n")
+	fmt.Fprintf(&b, "# 原有的钩子（如果有）保存为 %s%s，在审查之前执行。\n", name, chainedSuffix)
+	fmt.Fprintf(&b, "# 设置 %s=1 可以跳过本次审查。\n", skipEnv)
+	fmt.Fprintf(&b, "exec %s hook run --fail-on %s %s -- \"$@\"\n", shellQuote(exe), failOn, name)
+	return b.String()
+}
+
+// shellQuote 用单引号包裹 s，使其在 sh 中按字面值解析。
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runChainedHook 执行安装时被重命名的原有钩子（如果存在且可执行），参数与标准输入原样传入。
+// 原有钩子失败时返回错误，不再进行审查。
+func runChainedHook(cmd *cobra.Command, name string, args []string, input []byte) error {
+	dir, err := gitops.GetHooksDir()
+	if err != nil {
+		return fmt.Errorf("获取 hooks 目录失败: %w", err)
+	}
+
+	path := filepath.Join(dir, name+chainedSuffix)
+	info, err := os.Stat(path)
+	// 与 git 一致：不可执行的钩子不执行
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return nil
+	}
+
+	c := exec.Command(path, args...)
+	c.Stdin = bytes.NewReader(input)
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("原有的 %s 钩子执行失败: %w", name, err)
+	}
+	return nil
+}
+
+// pushRange 是一次推送中一个引用需要审查的提交范围。
+type pushRange struct {
+	ref        string
+	base, head string
+}
+
+// pushRanges 解析 pre-push 钩子的标准输入（每行 "<本地引用> <本地提交> <远程引用> <远程提交>"），
+// 返回每个被推送的引用中尚未出现在远程的提交范围。
+//
+// 删除远程引用的推送不需要审查；远程引用已存在时从两者的公共祖先开始，
+// 新建的远程引用（或本地没有远程提交）时从最早一个尚未推送到任何远程的提交开始。
+func pushRanges(input string) ([]pushRange, error) {
+	var (
+		ranges []pushRange
+		seen   = make(map[string]bool)
+	)
+	for _, line := range strings.Split(input, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		ref, local, remote := fields[2], fields[1], fields[3]
+		if isZeroHash(local) {
+			continue
+		}
+
+		var base string
+		if !isZeroHash(remote) && gitops.CommitExists(remote) {
+			if base, _ = gitops.GetMergeBase(remote, local); base == "" {
+				base = remote
+			}
+		} else {
+			var err error
+			if base, err = gitops.GetUnpushedBase(local); err != nil {
+				return nil, fmt.Errorf("确定 %s 的推送范围失败: %w", ref, err)
+			}
+		}
+
+		if base == "" || base == local || seen[base+".."+local] {
+			continue
+		}
+		seen[base+".."+local] = true
+		ranges = append(ranges, pushRange{ref: ref, base: base, head: local})
+	}
+	return ranges, nil
+}
+
+// isZeroHash 报告 hash 是否是 git 用来表示"不存在"的全零哈希。
+func isZeroHash(hash string) bool {
+	return strings.Trim(hash, "0") == ""
+}
+
+func init() {
+	hookInstallCmd.Flags().String("fail-on", "high", "存在不低于该严重程度（high / medium / low / info）的问题时阻止提交或推送；none 表示只在审查失败时阻止")
+	hookRunCmd.Flags().String("fail-on", "", "存在不低于该严重程度的问题时以非零状态退出")
+
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+	hookCmd.AddCommand(hookRunCmd)
+	rootCmd.AddCommand(hookCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+var prDescCmd = &cobra.Command{
+	Use:   "pr-desc",
+	Short: "根据当前分支的提交与 diff 生成 PR 描述",
+	Long: `根据 --head（默认 HEAD）相对 --base 新增的提交记录与全部变更生成 PR 描述，输出 Markdown 到标准输出。
+
+范围从 --base 与 --head 的公共祖先开始，与 PR 页面中展示的改动一致。
+描述按 Markdown 模板组织，内置模板包含标题、动机、改动列表、风险与测试清单；
+可以在配置文件的 pr.template 中或用 --template 指定自己的模板。
+请求同样经过外发策略、密钥脱敏、审计日志与预算检查。`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		base, _ := cmd.Flags().GetString("base")
+		head, _ := cmd.Flags().GetString("head")
+		if !gitops.CommitExists(base) {
+			return fmt.Errorf("找不到 base %q，可以用 --base 指定目标分支（如 origin/main）", base)
+		}
+		if !gitops.CommitExists(head) {
+			return fmt.Errorf("找不到 head %q", head)
+		}
+		mergeBase, err := gitops.GetMergeBase(base, head)
+		if err != nil {
+			return fmt.Errorf("%s 与 %s 没有公共祖先: %w", base, head, err)
+		}
+
+		runner, err := newRunner(cmd, mergeBase, head)
+		if err != nil {
+			return err
+		}
+
+		draft, err := runner.PRDescription()
+		if err != nil {
+			return err
+		}
+
+		reportDraft(cmd, runner, draft)
+		fmt.Fprintln(cmd.OutOrStdout(), draft.Message)
+		return nil
+	},
+}
+
+func init() {
+	prDescCmd.Flags().String("base", "main", "PR 的目标分支")
+	prDescCmd.Flags().String("head", "HEAD", "PR 的源分支")
+	prDescCmd.Flags().String("template", "", "Markdown 模板文件（默认取配置中的 pr.template，未配置时使用内置模板）")
+	prDescCmd.Flags().String("language", "", "PR 描述使用的语言（默认取配置中的 pr.language）")
+	prDescCmd.Flags().Bool("dry-run", false, "试运行：不调用 LLM，输出将要发送的确切提示词与估算的 token 数")
+	rootCmd.AddCommand(prDescCmd)
+}
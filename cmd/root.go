@@ -2,13 +2,23 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 
 	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/analysis"
+	"github.com/GuLuGuLuGit/review-go/internal/audit"
+	"github.com/GuLuGuLuGit/review-go/internal/cache"
 	"github.com/GuLuGuLuGit/review-go/internal/config"
+	"github.com/GuLuGuLuGit/review-go/internal/deps"
+	"github.com/GuLuGuLuGit/review-go/internal/history"
+	"github.com/GuLuGuLuGit/review-go/internal/policy"
+	"github.com/GuLuGuLuGit/review-go/internal/redact"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
 	"github.com/GuLuGuLuGit/review-go/internal/ui"
+	"github.com/GuLuGuLuGit/review-go/internal/usage"
 )
 
 var rootCmd = &cobra.Command{
@@ -19,20 +29,24 @@ var rootCmd = &cobra.Command{
 
 使用 'review-go config' 命令管理配置文件。`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// 读取配置并创建对应的 LLM Provider（支持 openai/deepseek/qwen 等）
-		cfg, err := config.Load()
+		failOn, err := parseFailOn(cmd)
 		if err != nil {
-			return fmt.Errorf("加载配置失败: %w", err)
+			return err
 		}
 
-		provider, err := ai.NewProvider(*cfg)
+		runner, err := newRunner(cmd, "", "")
 		if err != nil {
-			return fmt.Errorf("初始化 LLM Provider 失败: %w", err)
+			return err
+		}
+
+		headless, _ := cmd.Flags().GetBool("headless")
+		if headless {
+			return runHeadless(cmd.OutOrStdout(), runner, failOn)
 		}
 
 		// 启动 Bubble Tea TUI 主界面
-		m := ui.NewModel(provider)
-		p := tea.NewProgram(m, tea.WithAltScreen())
+		m := ui.NewModel(runner)
+		p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 		if _, err := p.Run(); err != nil {
 			return fmt.Errorf("启动 TUI 失败: %w", err)
@@ -42,6 +56,155 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// newRunner 按配置与 cmd 上的命令行参数创建审查流程。base 与 head 非空时审查这两个提交之间的变更，
+// 否则审查暂存区。cmd 上没有定义的参数按默认值处理。
+func newRunner(cmd *cobra.Command, base, head string) (*review.Runner, error) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	// 读取配置并创建对应的 LLM Provider（支持 openai/deepseek/qwen 等）；
+	// 试运行不访问 LLM，因此不要求配置 api_key
+	load := config.Load
+	if dryRun {
+		load = config.LoadWithoutKey
+	}
+	cfg, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	var provider ai.LLMProvider
+	if dryRun {
+		provider = ai.NewDryRunProvider(*cfg)
+	} else if provider, err = ai.NewProvider(*cfg); err != nil {
+		return nil, fmt.Errorf("初始化 LLM Provider 失败: %w", err)
+	}
+
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		return nil, fmt.Errorf("加载脱敏规则失败: %w", err)
+	}
+
+	egress, err := policy.New(cfg.Egress)
+	if err != nil {
+		return nil, fmt.Errorf("加载外发策略失败: %w", err)
+	}
+
+	// 开启审计日志时必须能够写入，否则拒绝运行，避免在不记录的情况下发送代码
+	var auditLog *audit.Logger
+	if cfg.Audit.Enabled && !dryRun {
+		if auditLog, err = audit.Open(cfg.Audit.Path, cfg.Audit.IncludeContent); err != nil {
+			return nil, fmt.Errorf("打开审计日志失败: %w", err)
+		}
+	}
+
+	noAnalysis, _ := cmd.Flags().GetBool("no-analysis")
+	fix, _ := cmd.Flags().GetBool("fix")
+	verifyTests, _ := cmd.Flags().GetBool("verify-tests")
+	showDismissed, _ := cmd.Flags().GetBool("show-dismissed")
+	noHistory, _ := cmd.Flags().GetBool("no-history")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	noOverall, _ := cmd.Flags().GetBool("no-overall")
+	maxFiles, _ := cmd.Flags().GetInt("max-files")
+	group, _ := cmd.Flags().GetString("group")
+	noDeps, _ := cmd.Flags().GetBool("no-deps")
+	noAPI, _ := cmd.Flags().GetBool("no-api")
+	switch group {
+	case "", review.GroupFile, review.GroupPackage, review.GroupModule:
+	default:
+		return nil, fmt.Errorf("未知的分组方式 %q（可选 %s / %s / %s）", group, review.GroupFile, review.GroupPackage, review.GroupModule)
+	}
+	opts := review.Options{
+		Fix:             fix,
+		VerifyFixes:     cfg.Fix.Verify,
+		VerifyTests:     cfg.Fix.VerifyTests || verifyTests,
+		DropFailedFixes: cfg.Fix.DropFailed,
+		ShowDismissed:   showDismissed,
+		Redactor:        redactor,
+		Policy:          egress,
+		DryRun:          dryRun,
+		Base:            base,
+		Head:            head,
+		Commit:          cfg.Commit,
+		PR:              cfg.PR,
+		Overall:         !noOverall,
+		MaxFiles:        maxFiles,
+		Group:           group,
+		Deps:            !noDeps,
+		DepsOptions:     deps.Options{VulnDB: cfg.Deps.VulnDB},
+		API:             !noAPI,
+	}
+	if lang, _ := cmd.Flags().GetString("language"); lang != "" {
+		opts.Commit.Language = lang
+		opts.PR.Language = lang
+	}
+	if style, _ := cmd.Flags().GetString("style"); style != "" {
+		opts.Commit.Style = style
+	}
+	// 命令行中的模板路径相对于当前目录，配置文件中的相对于仓库根目录
+	if tmpl, _ := cmd.Flags().GetString("template"); tmpl != "" {
+		if abs, err := filepath.Abs(tmpl); err == nil {
+			tmpl = abs
+		}
+		opts.PR.Template = tmpl
+	}
+	if !noAnalysis {
+		opts.Analysis = analysis.Options{
+			GoVet:        cfg.Analysis.GoVet,
+			Staticcheck:  cfg.Analysis.Staticcheck,
+			GolangciLint: cfg.Analysis.GolangciLint,
+		}
+	}
+
+	// 把每个文件的审查结果写入本地审查历史；历史不可用时不影响审查本身
+	if !noHistory && !dryRun {
+		if store, err := history.Open(); err == nil {
+			opts.Recorder = store.NewRecorder(ai.IdentityOf(provider))
+		}
+	}
+
+	// 相同输入复用缓存中的 LLM 回复；缓存目录不可用时直接请求 LLM
+	if cfg.Cache.Enabled && !noCache && !dryRun {
+		if c, err := cache.Open(int64(cfg.Cache.MaxSizeMB) << 20); err == nil {
+			opts.Cache = c
+		}
+	}
+
+	// 统计每次请求的 token 用量与费用，并在达到预算上限后停止发送请求；
+	// 用量记录不可用时只统计本次运行，按天的预算不生效
+	// 试运行中的用量只是估算：不计入当天用量，也不受预算限制
+	usageCfg := cfg.Usage
+	ledger, _ := usage.OpenLedger()
+	if dryRun {
+		usageCfg.Budget = config.BudgetConfig{}
+		ledger = nil
+	}
+	// 所有请求都先经过外发策略检查与脱敏再发送；审计日志在最内层，记录的是实际发出的内容；
+	// 预算与用量统计在最外层，被拒绝发送的请求不计入用量
+	guarded := policy.Wrap(redact.Wrap(audit.Wrap(provider, auditLog), redactor), egress)
+	meter, err := usage.NewMeter(guarded, usageCfg, ledger)
+	if err != nil {
+		return nil, err
+	}
+	return review.NewRunner(meter, opts), nil
+}
+
+func init() {
+	rootCmd.Flags().Bool("no-analysis", false, "跳过 go vet / staticcheck 等静态分析，仅使用 LLM 审查")
+	rootCmd.Flags().Bool("fix", false, "修复模式：让 LLM 为问题附带可直接应用的补丁")
+	rootCmd.Flags().Bool("verify-tests", false, "修复模式下，补丁编译通过后再对所在包执行 go test")
+	rootCmd.Flags().Bool("show-dismissed", false, "显示此前在 TUI 中标记为已忽略的问题")
+	rootCmd.Flags().Bool("no-history", false, "不把本次审查结果写入本地审查历史")
+	rootCmd.Flags().Bool("no-cache", false, "不使用 LLM 回复缓存，总是重新请求")
+	rootCmd.Flags().Bool("no-overall", false, "变更涉及多个文件时，不再对全部变更做一次查找跨文件问题的整体审查")
+	rootCmd.Flags().Int("max-files", 0, "按风险评分只审查最值得关注的 N 个文件，0 表示审查全部文件")
+	rootCmd.Flags().String("group", "", "审查单元：file 逐个文件（默认）/ package 按包目录 / module 按 go.mod 模块，把同一单元中的文件合在一起审查")
+	rootCmd.Flags().Bool("no-deps", false, "go.mod / go.sum 有变化时，不再检查依赖的版本、replace 指令、已知漏洞与许可证")
+	rootCmd.Flags().Bool("no-api", false, "不再比较有变更的包的导出 API，不报告删除或不兼容地修改了的导出标识符")
+	rootCmd.Flags().Bool("dry-run", false, "试运行：不调用任何 LLM，展示每个文件将要发送的确切提示词与估算的 token 数（无需 api_key）")
+	rootCmd.Flags().Bool("headless", false, "不启动 TUI，审查全部文件后把 Markdown 报告输出到标准输出")
+	rootCmd.Flags().String("fail-on", "", "无头模式下，存在不低于该严重程度（high / medium / low / info）的问题时以非零状态退出")
+}
+
 // Execute 是 CLI 的入口，由 main.go 调用。
 func Execute() error {
 	return rootCmd.Execute()
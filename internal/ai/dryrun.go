@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+)
+
+// DryRunProvider 不发送任何网络请求：把收到的请求原样渲染为 Markdown 作为回复，
+// 并在用量中返回估算的输入 token 数。用于 --dry-run，检查将要发送给 LLM 的确切内容。
+//
+// 它不需要 api_key，Identity 与按同一配置创建的真实 Provider 一致，
+// 因此外发策略、价格等按提供商与模型生效的配置在试运行中同样生效。
+type DryRunProvider struct {
+	identity Identity
+}
+
+// NewDryRunProvider 根据配置创建试运行 Provider。
+func NewDryRunProvider(cfg config.Config) *DryRunProvider {
+	name, baseURL, model := resolveEndpoint(cfg)
+	if name == "" {
+		name = "openai"
+	}
+	if baseURL == "" {
+		baseURL = openai.DefaultConfig("").BaseURL
+	}
+	return &DryRunProvider{identity: Identity{Provider: name, Model: model, BaseURL: baseURL}}
+}
+
+// Identity 实现 Identifiable。
+func (p *DryRunProvider) Identity() Identity {
+	return p.identity
+}
+
+// Chat 实现 LLMProvider。
+func (p *DryRunProvider) Chat(prompt string) (string, error) {
+	reply, _, err := p.ChatUsage(prompt)
+	return reply, err
+}
+
+// Converse 实现 Conversational。
+func (p *DryRunProvider) Converse(messages []Message) (string, error) {
+	reply, _, err := p.ConverseUsage(messages)
+	return reply, err
+}
+
+// ChatUsage 实现 Metered。
+func (p *DryRunProvider) ChatUsage(prompt string) (string, Usage, error) {
+	return p.Send(Request{Messages: []Message{{Role: RoleUser, Content: prompt}}})
+}
+
+// ConverseUsage 实现 Metered。
+func (p *DryRunProvider) ConverseUsage(messages []Message) (string, Usage, error) {
+	return p.Send(Request{Messages: messages})
+}
+
+// Send 实现 Sender：返回描述该请求的 Markdown，不发送任何内容。
+func (p *DryRunProvider) Send(req Request) (string, Usage, error) {
+	tokens := 0
+	for _, m := range req.Messages {
+		tokens += EstimateTokens(m.Content)
+	}
+
+	var b strings.Builder
+	b.WriteString("## 试运行：将要发送的请求\n\n")
+	fmt.Fprintf(&b, "- 提供商：%s / %s（%s）\n", p.identity.Provider, p.identity.Model, p.identity.BaseURL)
+	if len(req.Files) > 0 {
+		fmt.Fprintf(&b, "- 文件：%s\n", strings.Join(req.Files, "、"))
+	}
+	fmt.Fprintf(&b, "- 估算输入 token：约 %d（按字符数粗略估算，实际以提供商的分词为准）\n", tokens)
+
+	for i, m := range req.Messages {
+		fence := codeFence(m.Content)
+		fmt.Fprintf(&b, "\n### 消息 %d（%s，约 %d tokens）\n\n%stext\n%s\n%s\n",
+			i+1, m.Role, EstimateTokens(m.Content), fence, m.Content, fence)
+	}
+
+	return b.String(), Usage{PromptTokens: tokens}, nil
+}
+
+// EstimateTokens 粗略估算 text 的 token 数：ASCII 字符约 4 个一个 token，
+// 其他字符（主要是中文）约 1 个一个 token。误差通常在 20% 以内，只用于预估。
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// codeFence 返回比 content 中最长的连续反引号更长的代码块围栏，保证内容原样显示。
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
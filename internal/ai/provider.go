@@ -20,6 +20,204 @@ type LLMProvider interface {
 	Chat(prompt string) (string, error)
 }
 
+// Message 是多轮对话中的一条消息，Role 取值与 OpenAI 接口一致：system / user / assistant。
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// 多轮对话中使用的角色名称。
+const (
+	RoleSystem    = openai.ChatMessageRoleSystem
+	RoleUser      = openai.ChatMessageRoleUser
+	RoleAssistant = openai.ChatMessageRoleAssistant
+)
+
+// Conversational 是支持多轮对话的扩展接口。
+//
+// 并非所有 LLMProvider 都需要实现它：调用方应通过 Converse 函数使用，
+// 未实现时会自动退化为单条 prompt。
+type Conversational interface {
+	// Converse 发送完整的对话历史，返回模型的下一条回复。
+	Converse(messages []Message) (string, error)
+}
+
+// Identity 描述一个 Provider 实际使用的提供商名称、模型与接口地址，用于审查历史、缓存的 key 与审计日志。
+type Identity struct {
+	Provider string
+	Model    string
+	BaseURL  string
+}
+
+// Identifiable 是能够报告自身 Identity 的扩展接口，调用方应通过 IdentityOf 使用。
+type Identifiable interface {
+	Identity() Identity
+}
+
+// IdentityOf 返回 provider 的 Identity；provider 未实现 Identifiable 时提供商与模型均为 "unknown"。
+func IdentityOf(provider LLMProvider) Identity {
+	if id, ok := provider.(Identifiable); ok {
+		return id.Identity()
+	}
+	return Identity{Provider: "unknown", Model: "unknown"}
+}
+
+// Usage 是一次或多次请求消耗的 token 数与费用。Cost 由 usage 包按配置的模型价格计算，
+// 提供商本身只填写 token 数。
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
+}
+
+// Total 返回输入与输出 token 之和。
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add 把 o 累加到 u 上。
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.Cost += o.Cost
+}
+
+// Metered 是能够报告每次请求 token 用量的扩展接口，调用方应通过 ChatUsage / ConverseUsage 使用。
+type Metered interface {
+	ChatUsage(prompt string) (string, Usage, error)
+	ConverseUsage(messages []Message) (string, Usage, error)
+}
+
+// ChatUsage 与 provider.Chat 相同，同时返回本次请求的用量；provider 未实现 Metered 时用量为零。
+func ChatUsage(provider LLMProvider, prompt string) (string, Usage, error) {
+	if provider == nil {
+		return "", Usage{}, errors.New("LLM Provider 未初始化")
+	}
+	if m, ok := provider.(Metered); ok {
+		return m.ChatUsage(prompt)
+	}
+	reply, err := provider.Chat(prompt)
+	return reply, Usage{}, err
+}
+
+// ConverseUsage 与 Converse 相同，同时返回本次请求的用量；provider 未实现 Metered 时用量为零。
+func ConverseUsage(provider LLMProvider, messages []Message) (string, Usage, error) {
+	if provider == nil {
+		return "", Usage{}, errors.New("LLM Provider 未初始化")
+	}
+	if m, ok := provider.(Metered); ok {
+		return m.ConverseUsage(messages)
+	}
+	reply, err := Converse(provider, messages)
+	return reply, Usage{}, err
+}
+
+// Request 是一次发送给 LLM 的完整请求。
+//
+// Files 是请求中包含其代码的文件（相对仓库根目录），供外发策略、审计等包装在 provider 外层的
+// 组件使用；不涉及具体文件的请求可以留空。
+type Request struct {
+	Messages []Message
+	Files    []string
+}
+
+// Sender 是接受完整 Request 的扩展接口，调用方应通过 Send 使用。
+//
+// 包装其他 provider 的组件（用量统计、脱敏、外发策略等）应嵌入 Middleware 并通过 Send 转发，
+// 这样 Files 等元数据才能一路传递到最内层。
+type Sender interface {
+	Send(req Request) (string, Usage, error)
+}
+
+// Send 发送 req，返回回复与本次请求的用量。
+//
+// provider 实现了 Sender 时直接调用；否则只有一条用户消息时通过 ChatUsage 发送，
+// 其余情况通过 ConverseUsage 发送，此时 Files 等元数据会被忽略。
+func Send(provider LLMProvider, req Request) (string, Usage, error) {
+	if provider == nil {
+		return "", Usage{}, errors.New("LLM Provider 未初始化")
+	}
+	if s, ok := provider.(Sender); ok {
+		return s.Send(req)
+	}
+	if len(req.Messages) == 1 && req.Messages[0].Role == RoleUser {
+		return ChatUsage(provider, req.Messages[0].Content)
+	}
+	return ConverseUsage(provider, req.Messages)
+}
+
+// Middleware 是包装其他 provider 的组件（用量统计、脱敏、外发策略、审计等）的公共部分，
+// 嵌入后即实现 LLMProvider、Conversational、Identifiable、Metered 与 Sender，可以直接替换被包装的 provider。
+//
+// 所有请求最终都经由 SendFunc 发送，包装组件只需提供它；Identity 取自 Next。
+// SendFunc 为 nil 时原样转发给 Next。
+type Middleware struct {
+	Next     LLMProvider
+	SendFunc func(req Request) (string, Usage, error)
+}
+
+// Identity 实现 Identifiable，返回被包装 provider 的 Identity。
+func (m Middleware) Identity() Identity {
+	return IdentityOf(m.Next)
+}
+
+// Chat 实现 LLMProvider。
+func (m Middleware) Chat(prompt string) (string, error) {
+	reply, _, err := m.ChatUsage(prompt)
+	return reply, err
+}
+
+// Converse 实现 Conversational。
+func (m Middleware) Converse(messages []Message) (string, error) {
+	reply, _, err := m.ConverseUsage(messages)
+	return reply, err
+}
+
+// ChatUsage 实现 Metered。
+func (m Middleware) ChatUsage(prompt string) (string, Usage, error) {
+	return m.Send(Request{Messages: []Message{{Role: RoleUser, Content: prompt}}})
+}
+
+// ConverseUsage 实现 Metered。
+func (m Middleware) ConverseUsage(messages []Message) (string, Usage, error) {
+	return m.Send(Request{Messages: messages})
+}
+
+// Send 实现 Sender。
+func (m Middleware) Send(req Request) (string, Usage, error) {
+	if m.SendFunc == nil {
+		return Send(m.Next, req)
+	}
+	return m.SendFunc(req)
+}
+
+// Converse 使用 provider 进行多轮对话。
+//
+// provider 实现了 Conversational 时直接发送消息列表；否则把历史按角色拼接为一条 prompt
+// 通过 Chat 发送，保证任意 LLMProvider 都能参与对话。
+func Converse(provider LLMProvider, messages []Message) (string, error) {
+	if provider == nil {
+		return "", errors.New("LLM Provider 未初始化")
+	}
+	if c, ok := provider.(Conversational); ok {
+		return c.Converse(messages)
+	}
+
+	var b strings.Builder
+	for _, msg := range messages {
+		switch msg.Role {
+		case RoleSystem:
+			b.WriteString(msg.Content)
+		case RoleAssistant:
+			b.WriteString("助手：" + msg.Content)
+		default:
+			b.WriteString("用户：" + msg.Content)
+		}
+		b.WriteString("\n\n")
+	}
+	return provider.Chat(b.String())
+}
+
 // OpenAICompatibleProvider 使用 go-openai 客户端访问任意 OpenAI 兼容的后端。
 //
 // 通过配置 BaseURL、APIKey、Model 即可接入：
@@ -27,8 +225,11 @@ type LLMProvider interface {
 //   - DeepSeek: https://api.deepseek.com
 //   - 通义千问 / Qwen (兼容模式): https://dashscope.aliyuncs.com/compatible-mode/v1
 type OpenAICompatibleProvider struct {
-	client *openai.Client
-	model  string
+	client  *openai.Client
+	model   string
+	baseURL string
+	// name 是配置中的提供商名称，仅用于 Identity；直接调用 NewOpenAICompatibleProvider 时为 "openai"。
+	name string
 }
 
 // NewOpenAICompatibleProvider 创建一个基于 go-openai 的通用 Provider。
@@ -55,49 +256,109 @@ func NewOpenAICompatibleProvider(baseURL, apiKey, model string) (*OpenAICompatib
 	}
 
 	return &OpenAICompatibleProvider{
-		client: client,
-		model:  model,
+		client:  client,
+		model:   model,
+		baseURL: cfg.BaseURL,
+		name:    "openai",
 	}, nil
 }
 
+// Identity 实现 Identifiable。
+func (p *OpenAICompatibleProvider) Identity() Identity {
+	return Identity{Provider: p.name, Model: p.model, BaseURL: p.baseURL}
+}
+
 // Chat 调用兼容的 Chat Completions 接口，返回单轮对话结果。
 func (p *OpenAICompatibleProvider) Chat(prompt string) (string, error) {
+	reply, _, err := p.ChatUsage(prompt)
+	return reply, err
+}
+
+// ChatUsage 实现 Metered：与 Chat 相同，同时返回接口报告的 token 用量。
+func (p *OpenAICompatibleProvider) ChatUsage(prompt string) (string, Usage, error) {
 	if p == nil || p.client == nil {
-		return "", errors.New("OpenAICompatibleProvider 未正确初始化：client 为空")
+		return "", Usage{}, errors.New("OpenAICompatibleProvider 未正确初始化：client 为空")
 	}
 
 	prompt = strings.TrimSpace(prompt)
 	if prompt == "" {
-		return "", errors.New("prompt 不能为空")
+		return "", Usage{}, errors.New("prompt 不能为空")
+	}
+
+	return p.complete([]openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		},
+	})
+}
+
+// Converse 调用兼容的 Chat Completions 接口，发送完整的多轮对话历史。
+func (p *OpenAICompatibleProvider) Converse(messages []Message) (string, error) {
+	reply, _, err := p.ConverseUsage(messages)
+	return reply, err
+}
+
+// ConverseUsage 实现 Metered：与 Converse 相同，同时返回接口报告的 token 用量。
+func (p *OpenAICompatibleProvider) ConverseUsage(messages []Message) (string, Usage, error) {
+	if p == nil || p.client == nil {
+		return "", Usage{}, errors.New("OpenAICompatibleProvider 未正确初始化：client 为空")
+	}
+
+	if len(messages) == 0 {
+		return "", Usage{}, errors.New("对话消息不能为空")
+	}
+
+	msgs := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, m := range messages {
+		msgs = append(msgs, openai.ChatCompletionMessage{
+			Role:    m.Role,
+			Content: m.Content,
+		})
+	}
+
+	return p.complete(msgs)
+}
+
+// Send 实现 Sender。OpenAICompatibleProvider 本身不使用 Files。
+func (p *OpenAICompatibleProvider) Send(req Request) (string, Usage, error) {
+	if len(req.Messages) == 1 && req.Messages[0].Role == RoleUser {
+		return p.ChatUsage(req.Messages[0].Content)
 	}
+	return p.ConverseUsage(req.Messages)
+}
 
+// complete 发送一次 Chat Completions 请求，返回第一条回复的内容与接口报告的 token 用量。
+//
+// 即使回复内容为空，只要请求成功也会返回用量，因为这部分 token 同样已经计费。
+func (p *OpenAICompatibleProvider) complete(messages []openai.ChatCompletionMessage) (string, Usage, error) {
 	req := openai.ChatCompletionRequest{
 		Model:       p.model,
 		Temperature: float32(defaultTemperature),
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
+		Messages:    messages,
 	}
 
 	ctx := context.Background()
 	resp, err := p.client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("调用 OpenAI 兼容接口失败: %w", err)
+		return "", Usage{}, fmt.Errorf("调用 OpenAI 兼容接口失败: %w", err)
+	}
+
+	usage := Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", errors.New("LLM 返回结果为空：没有任何 choices")
+		return "", usage, errors.New("LLM 返回结果为空：没有任何 choices")
 	}
 
 	content := strings.TrimSpace(resp.Choices[0].Message.Content)
 	if content == "" {
-		return "", errors.New("LLM 返回的内容为空")
+		return "", usage, errors.New("LLM 返回的内容为空")
 	}
 
-	return content, nil
+	return content, usage, nil
 }
 
 // NewProvider 根据配置创建一个合适的 LLMProvider 实例。
@@ -120,10 +381,25 @@ func NewProvider(cfg config.Config) (LLMProvider, error) {
 		return nil, errors.New("配置中的 api_key 不能为空")
 	}
 
-	baseURL := strings.TrimSpace(cfg.BaseURL)
-	model := strings.TrimSpace(cfg.Model)
+	providerName, baseURL, model := resolveEndpoint(cfg)
+
+	p, err := NewOpenAICompatibleProvider(baseURL, apiKey, model)
+	if err != nil {
+		return nil, err
+	}
+	if providerName != "" {
+		p.name = providerName
+	}
+	return p, nil
+}
+
+// resolveEndpoint 返回配置对应的提供商名称（小写，可能为空）、接口地址与模型，
+// 已知厂商缺失的 BaseURL 与 Model 在此补齐。
+func resolveEndpoint(cfg config.Config) (providerName, baseURL, model string) {
+	baseURL = strings.TrimSpace(cfg.BaseURL)
+	model = strings.TrimSpace(cfg.Model)
 
-	providerName := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	providerName = strings.ToLower(strings.TrimSpace(cfg.Provider))
 
 	switch providerName {
 	case "deepseek":
@@ -160,5 +436,5 @@ func NewProvider(cfg config.Config) (LLMProvider, error) {
 		}
 	}
 
-	return NewOpenAICompatibleProvider(baseURL, apiKey, model)
+	return providerName, baseURL, model
 }
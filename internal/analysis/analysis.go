@@ -0,0 +1,184 @@
+package analysis
+
+import (
+	"bufio"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic 是静态分析工具报告的一条问题。
+//
+// File 为相对于仓库根目录、使用 "/" 分隔的路径，与 git diff --name-only 的输出一致，
+// 方便按文件与变更行进行匹配。
+type Diagnostic struct {
+	Tool    string
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Options 控制启用哪些分析工具。
+//
+// go vet 随 Go 工具链提供；staticcheck 与 golangci-lint 只有在 PATH 中存在时才会运行，
+// 未安装时静默跳过。
+type Options struct {
+	GoVet        bool
+	Staticcheck  bool
+	GolangciLint bool
+}
+
+// tool 描述一个可以对若干包运行的外部分析工具。
+type tool struct {
+	name string
+	bin  string
+	args func(pkgs []string) []string
+}
+
+var tools = []struct {
+	enabled func(Options) bool
+	tool    tool
+}{
+	{
+		enabled: func(o Options) bool { return o.GoVet },
+		tool: tool{
+			name: "go vet",
+			bin:  "go",
+			args: func(pkgs []string) []string { return append([]string{"vet"}, pkgs...) },
+		},
+	},
+	{
+		enabled: func(o Options) bool { return o.Staticcheck },
+		tool: tool{
+			name: "staticcheck",
+			bin:  "staticcheck",
+			args: func(pkgs []string) []string { return pkgs },
+		},
+	},
+	{
+		enabled: func(o Options) bool { return o.GolangciLint },
+		tool: tool{
+			name: "golangci-lint",
+			bin:  "golangci-lint",
+			args: func(pkgs []string) []string {
+				return append([]string{"run", "--color", "never"}, pkgs...)
+			},
+		},
+	},
+}
+
+// diagLineRe 匹配 "path/to/file.go:12:3: message" 或 "path/to/file.go:12: message"。
+// go vet 在类型检查失败时会输出 "vet: file.go:1:2: ..."，因此允许一个可选的工具前缀。
+var diagLineRe = regexp.MustCompile(`^(?:[a-z]+: )?(.+?\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// Run 在仓库根目录 root 下，对 files 所在的 Go 包运行启用的分析工具，
+// 返回按文件分组的诊断结果。
+//
+// 分析工具读取的是工作区中的文件，而不是暂存区；当两者不一致时，行号可能有偏差，
+// 调用方应当结合变更行再做一次过滤。单个工具执行失败（未安装、编译错误导致提前退出等）
+// 不会中断其他工具，能解析出的诊断照常返回。
+func Run(root string, files []string, opts Options) map[string][]Diagnostic {
+	result := make(map[string][]Diagnostic)
+
+	pkgs := packagesOf(files)
+	if len(pkgs) == 0 {
+		return result
+	}
+
+	for _, t := range tools {
+		if !t.enabled(opts) {
+			continue
+		}
+		if _, err := exec.LookPath(t.tool.bin); err != nil {
+			continue
+		}
+
+		for _, d := range runTool(root, t.tool, pkgs) {
+			result[d.File] = append(result[d.File], d)
+		}
+	}
+
+	for f := range result {
+		sort.SliceStable(result[f], func(i, j int) bool {
+			return result[f][i].Line < result[f][j].Line
+		})
+	}
+
+	return result
+}
+
+// runTool 执行单个工具并解析其输出。工具的退出码被忽略：
+// go vet / staticcheck 在发现问题时本身就会以非零状态退出。
+func runTool(root string, t tool, pkgs []string) []Diagnostic {
+	cmd := exec.Command(t.bin, t.args(pkgs)...)
+	cmd.Dir = root
+	out, _ := cmd.CombinedOutput()
+
+	var diags []Diagnostic
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		m := diagLineRe.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		col, _ := strconv.Atoi(m[3])
+
+		diags = append(diags, Diagnostic{
+			Tool:    t.name,
+			File:    normalizePath(root, m[1]),
+			Line:    line,
+			Column:  col,
+			Message: strings.TrimSpace(m[4]),
+		})
+	}
+
+	return diags
+}
+
+// packagesOf 把文件列表转换为去重后的相对包路径（如 "./internal/ui"）。
+func packagesOf(files []string) []string {
+	seen := make(map[string]struct{})
+	var pkgs []string
+
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".go") {
+			continue
+		}
+
+		dir := path.Dir(filepath.ToSlash(f))
+		pkg := "./" + dir
+		if dir == "." {
+			pkg = "."
+		}
+
+		if _, ok := seen[pkg]; ok {
+			continue
+		}
+		seen[pkg] = struct{}{}
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// normalizePath 把工具输出中的路径（可能是绝对路径或带 "./" 前缀）
+// 统一为相对于仓库根目录的 "/" 分隔路径。
+func normalizePath(root, p string) string {
+	if filepath.IsAbs(p) {
+		if rel, err := filepath.Rel(root, p); err == nil {
+			p = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "./")
+}
@@ -0,0 +1,367 @@
+// Package apidiff 从 Go 源码中提取包的导出 API，并比较两个版本之间的不兼容变化，
+// 用于在审查时报告会导致下游代码无法编译的修改。
+//
+// Parse 只做语法分析，不做类型检查，也不需要依赖包的源码；Compare 按名称比较两个版本的导出标识符。
+package apidiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Object 是包中的一个导出标识符：包级的函数、类型、常量、变量，或导出类型的导出方法、字段。
+//
+// - Name: 包级标识符为其名称，方法与字段为 "类型.名称"
+// - Kind: func / method / type / field / const / var
+// - Sig: 用于比较的签名或类型，参数名不参与比较；常量与变量没有显式写出类型时为空
+// - PtrRecv: 方法的接收者是否为指针
+// - File / Line: 声明所在的文件与行号
+type Object struct {
+	Name    string
+	Kind    string
+	Sig     string
+	PtrRecv bool
+	File    string
+	Line    int
+}
+
+// Package 是从一个包的源码中提取出的导出 API。
+//
+// ifaces 记录可以在包外实现的导出接口（没有未导出的方法），向这些接口添加方法会破坏包外的实现。
+type Package struct {
+	Name    string
+	Objects map[string]Object
+
+	ifaces map[string]bool
+}
+
+// Parse 从 files（文件路径 → 源码）中提取包的导出 API。
+//
+// 与 go build 一样按当前平台的 GOOS、GOARCH 与构建约束选择文件（见 buildContext），
+// 因此 _test.go、带 //go:build ignore 的文件以及只用于其他平台的文件都不参与比较。
+// 选中的文件包名不一致时返回错误。
+func Parse(files map[string]string) (*Package, error) {
+	pkg := &Package{Objects: make(map[string]Object), ifaces: make(map[string]bool)}
+	fset := token.NewFileSet()
+	ctx := buildContext(files)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		ok, err := ctx.MatchFile(path.Dir(name), path.Base(name))
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", name, err)
+		}
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, files[name], parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", name, err)
+		}
+		switch {
+		case pkg.Name == "":
+			pkg.Name = f.Name.Name
+		case pkg.Name != f.Name.Name:
+			return nil, fmt.Errorf("%s 的包名 %s 与其他文件的 %s 不一致", name, f.Name.Name, pkg.Name)
+		}
+		for _, decl := range f.Decls {
+			pkg.addDecl(fset, name, decl)
+		}
+	}
+	return pkg, nil
+}
+
+// buildContext 返回从 files 中读取源码、按当前平台匹配文件的 build.Context。
+// 包含 import "C" 的文件同样参与比较：是否启用 cgo 不影响包对外提供的 API。
+func buildContext(files map[string]string) build.Context {
+	ctx := build.Default
+	ctx.CgoEnabled = true
+	ctx.JoinPath = path.Join
+	ctx.OpenFile = func(name string) (io.ReadCloser, error) {
+		src, ok := files[name]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(src)), nil
+	}
+	return ctx
+}
+
+// addDecl 把 decl 中的导出标识符加入 pkg。
+func (pkg *Package) addDecl(fset *token.FileSet, file string, decl ast.Decl) {
+	add := func(name, kind, sig string, ident *ast.Ident) Object {
+		obj := Object{Name: name, Kind: kind, Sig: sig, File: file, Line: fset.Position(ident.Pos()).Line}
+		pkg.Objects[name] = obj
+		return obj
+	}
+
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if !d.Name.IsExported() {
+			return
+		}
+		if d.Recv == nil {
+			renameTypeParams(d.Type, typeParamNames(d.Type.TypeParams))
+			add(d.Name.Name, "func", funcSig(d.Type), d.Name)
+			return
+		}
+		recv, ptr := receiver(d.Recv)
+		if recv == "" || !ast.IsExported(recv) {
+			return
+		}
+		renameTypeParams(d.Type, recvTypeParamNames(d.Recv))
+		obj := add(recv+"."+d.Name.Name, "method", funcSig(d.Type), d.Name)
+		obj.PtrRecv = ptr
+		pkg.Objects[obj.Name] = obj
+
+	case *ast.GenDecl:
+		var lastType ast.Expr // 常量块中省略类型与值的常量沿用上一个的类型
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.IsExported() {
+					pkg.addType(add, s)
+				}
+			case *ast.ValueSpec:
+				kind := "var"
+				if d.Tok == token.CONST {
+					kind = "const"
+					if s.Type != nil || len(s.Values) > 0 {
+						lastType = s.Type
+					}
+				}
+				typ := s.Type
+				if kind == "const" {
+					typ = lastType
+				}
+				sig := ""
+				if typ != nil {
+					sig = types.ExprString(typ)
+				}
+				for _, n := range s.Names {
+					if n.IsExported() {
+						add(n.Name, kind, sig, n)
+					}
+				}
+			}
+		}
+	}
+}
+
+// addType 把导出类型 s 及其导出字段、接口方法加入 pkg。
+func (pkg *Package) addType(add func(name, kind, sig string, ident *ast.Ident) Object, s *ast.TypeSpec) {
+	name := s.Name.Name
+	names := typeParamNames(s.TypeParams)
+	renameTypeParams(s.TypeParams, names)
+	renameTypeParams(s.Type, names)
+	params := fieldList(s.TypeParams, true)
+
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		add(name, "type", params+"struct", s.Name)
+		for _, f := range t.Fields.List {
+			typ := types.ExprString(f.Type)
+			if len(f.Names) == 0 {
+				// 嵌入字段以类型名作为字段名
+				if embedded := embeddedName(f.Type); ast.IsExported(embedded) {
+					add(name+"."+embedded, "field", typ, &ast.Ident{Name: embedded, NamePos: f.Type.Pos()})
+				}
+				continue
+			}
+			for _, n := range f.Names {
+				if n.IsExported() {
+					add(name+"."+n.Name, "field", typ, n)
+				}
+			}
+		}
+
+	case *ast.InterfaceType:
+		add(name, "type", params+"interface", s.Name)
+		implementable := true
+		for _, m := range t.Methods.List {
+			if len(m.Names) == 0 {
+				embedded := types.ExprString(m.Type)
+				add(name+"."+embedded, "embed", embedded, &ast.Ident{Name: embedded, NamePos: m.Type.Pos()})
+				continue
+			}
+			for _, n := range m.Names {
+				if !n.IsExported() {
+					implementable = false
+					continue
+				}
+				ft, ok := m.Type.(*ast.FuncType)
+				if !ok {
+					continue
+				}
+				add(name+"."+n.Name, "method", funcSig(ft), n)
+			}
+		}
+		pkg.ifaces[name] = implementable
+
+	default:
+		sig := params + types.ExprString(s.Type)
+		if s.Assign.IsValid() {
+			sig = "= " + sig
+		}
+		add(name, "type", sig, s.Name)
+	}
+}
+
+// receiver 返回方法接收者的类型名（去掉指针与类型参数），以及接收者是否为指针。
+func receiver(recv *ast.FieldList) (string, bool) {
+	if recv == nil || len(recv.List) == 0 {
+		return "", false
+	}
+	typ := recv.List[0].Type
+	ptr := false
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ, ptr = star.X, true
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name, ptr
+	}
+	return "", ptr
+}
+
+// typeParamNames 返回类型参数列表中每个参数的名称到其位置名称（P0、P1……）的映射。
+func typeParamNames(fl *ast.FieldList) map[string]string {
+	if fl == nil {
+		return nil
+	}
+	names := make(map[string]string)
+	for _, f := range fl.List {
+		for _, n := range f.Names {
+			names[n.Name] = fmt.Sprintf("P%d", len(names))
+		}
+	}
+	return names
+}
+
+// recvTypeParamNames 与 typeParamNames 相同，但类型参数来自泛型类型的方法接收者，例如 (s *S[K, V])。
+func recvTypeParamNames(recv *ast.FieldList) map[string]string {
+	if recv == nil || len(recv.List) == 0 {
+		return nil
+	}
+	typ := recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	var indices []ast.Expr
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+	names := make(map[string]string)
+	for i, idx := range indices {
+		if id, ok := idx.(*ast.Ident); ok && id.Name != "_" {
+			names[id.Name] = fmt.Sprintf("P%d", i)
+		}
+	}
+	return names
+}
+
+// renameTypeParams 把 node 中引用类型参数的标识符改为 names 中的位置名称，
+// 使类型参数改名不被当作签名的变化，而约束的变化仍能比较出来。
+// 字段名、方法名与参数名不是类型，保持不变；pkg.Name 形式的限定标识符不会引用类型参数。
+func renameTypeParams(node ast.Node, names map[string]string) {
+	if node == nil || len(names) == 0 {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			return false
+		case *ast.Field:
+			renameTypeParams(n.Type, names)
+			return false
+		case *ast.Ident:
+			if p, ok := names[n.Name]; ok {
+				n.Name = p
+			}
+		}
+		return true
+	})
+}
+
+// embeddedName 返回嵌入字段的字段名：类型名（去掉指针、包名与类型参数）。
+func embeddedName(typ ast.Expr) string {
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.SelectorExpr:
+			return t.Sel.Name
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// funcSig 返回函数类型去掉参数名之后的写法，例如 "[P0 any](int, ...string) (P0, error)"。
+func funcSig(ft *ast.FuncType) string {
+	sig := fieldList(ft.TypeParams, true) + "(" + fieldList(ft.Params, false) + ")"
+	if ft.Results != nil && len(ft.Results.List) > 0 {
+		results := fieldList(ft.Results, false)
+		if len(ft.Results.List) == 1 && len(ft.Results.List[0].Names) <= 1 {
+			sig += " " + results
+		} else {
+			sig += " (" + results + ")"
+		}
+	}
+	return sig
+}
+
+// fieldList 把参数或类型参数列表写成以逗号分隔的类型。类型参数（typeParams 为 true）以位置名称
+// P0、P1……代替原来的名称（约束中的引用已由 renameTypeParams 替换），因为约束可能引用它们；
+// 普通参数只保留类型，参数名的变化不影响调用方。
+func fieldList(fl *ast.FieldList, typeParams bool) string {
+	if fl == nil || len(fl.List) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, f := range fl.List {
+		typ := types.ExprString(f.Type)
+		n := max(len(f.Names), 1)
+		for i := 0; i < n; i++ {
+			if typeParams && i < len(f.Names) {
+				parts = append(parts, fmt.Sprintf("P%d %s", len(parts), typ))
+			} else {
+				parts = append(parts, typ)
+			}
+		}
+	}
+	if typeParams {
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,138 @@
+package apidiff
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// parse 把 src 作为 p/p.go 解析为 Package，解析失败时终止测试。
+func parse(t *testing.T, src string) *Package {
+	t.Helper()
+	pkg, err := Parse(map[string]string{"p/p.go": "package p\n\n" + src})
+	if err != nil {
+		t.Fatalf("Parse 返回错误：%v", err)
+	}
+	return pkg
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string // 报告了变化的标识符
+	}{
+		{"删除导出函数", "func F() {}\nfunc G() {}", "func G() {}", []string{"F"}},
+		{"修改参数类型", "func F(int) {}", "func F(string) {}", []string{"F"}},
+		{"增加参数", "func F(a int) {}", "func F(a int, b bool) {}", []string{"F"}},
+		{"修改返回值类型", "func F() int { return 0 }", "func F() error { return nil }", []string{"F"}},
+		{"只修改参数名", "func F(a int) {}", "func F(b int) {}", nil},
+		{"新增导出函数", "func F() {}", "func F() {}\nfunc G() {}", nil},
+
+		{"值接收者改为指针接收者", "type T struct{}\nfunc (T) M() {}", "type T struct{}\nfunc (*T) M() {}", []string{"T.M"}},
+		{"指针接收者改为值接收者", "type T struct{}\nfunc (*T) M() {}", "type T struct{}\nfunc (T) M() {}", nil},
+
+		{"导出接口新增方法", "type I interface{ M() }", "type I interface {\n\tM()\n\tN()\n}", []string{"I.N"}},
+		{"不可在包外实现的接口新增方法", "type I interface{ m() }", "type I interface {\n\tm()\n\tN()\n}", nil},
+		{"接口删除方法", "type I interface {\n\tM()\n\tN()\n}", "type I interface{ M() }", []string{"I.N"}},
+
+		{"删除导出字段", "type S struct{ A, B int }", "type S struct{ A int }", []string{"S.B"}},
+		{"修改字段类型", "type S struct{ A int }", "type S struct{ A string }", []string{"S.A"}},
+		{"新增字段", "type S struct{ A int }", "type S struct{ A, B int }", nil},
+		{"删除类型时不再报告其成员", "type S struct{ A int }\nfunc (S) M() {}", "", []string{"S"}},
+		{"结构体改为接口", "type S struct{ A int }", "type S interface{ M() }", []string{"S"}},
+
+		{"未导出标识符的变化", "func f(int) {}\ntype s struct{ a int }\nvar v int", "func f(string) {}\nvar v string", nil},
+		{"导出类型的未导出字段", "type S struct{ A, b int }", "type S struct{ A int }", nil},
+		{"修改常量类型", "const C int = 1", "const C int64 = 1", []string{"C"}},
+		{"无类型常量改变值", "const C = 1", "const C = 2", nil},
+
+		{"类型参数改名", "func F[T any](T) T { var z T; return z }", "func F[U any](U) U { var z U; return z }", nil},
+		{"约束中引用其他类型参数时改名", "func F[S ~[]E, E any](S) E { panic(0) }", "func F[X ~[]Y, Y any](X) Y { panic(0) }", nil},
+		{"修改类型参数约束", "func F[T any](T) {}", "func F[T comparable](T) {}", []string{"F"}},
+		{"交换类型参数顺序", "func F[K comparable, V any](K, V) {}", "func F[V any, K comparable](K, V) {}", []string{"F"}},
+		{"泛型类型的类型参数改名",
+			"type S[T any] struct{ V T }\nfunc (s S[T]) Get() T { return s.V }",
+			"type S[U any] struct{ V U }\nfunc (s S[X]) Get() X { return s.V }", nil},
+		{"泛型类型的字段名与类型参数同名", "type S[T any] struct{ T T }", "type S[U any] struct{ T U }", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range Compare(parse(t, tt.before), parse(t, tt.after)) {
+				got = append(got, c.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Compare 报告了 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareMessage(t *testing.T) {
+	changes := Compare(parse(t, "func F(int) error { return nil }"), parse(t, "func F(int, bool) error { return nil }"))
+	if len(changes) != 1 {
+		t.Fatalf("Compare 返回 %d 个变化，期望 1 个", len(changes))
+	}
+	c := changes[0]
+	want := "函数 F 的签名由 (int) error 变为 (int, bool) error"
+	if c.Message != want {
+		t.Errorf("Message = %q，期望 %q", c.Message, want)
+	}
+	if c.Old == nil || c.New == nil || c.Old.File != "p/p.go" || c.New.Line != 3 {
+		t.Errorf("Old / New = %+v / %+v，期望都指向 p/p.go 第 3 行", c.Old, c.New)
+	}
+}
+
+func TestParseBuildConstraints(t *testing.T) {
+	// 选一个与当前平台不同的 GOOS，对应的文件不应参与比较
+	other := "windows"
+	if runtime.GOOS == other {
+		other = "linux"
+	}
+
+	pkg, err := Parse(map[string]string{
+		"p/a.go":                    "package p\n\nfunc A() {}\n",
+		"p/gen.go":                  "//go:build ignore\n\npackage main\n\nfunc Gen() {}\n",
+		"p/w_" + other + ".go":      "package p\n\nfunc Other() {}\n",
+		"p/tagged.go":               "//go:build " + other + "\n\npackage p\n\nfunc Tagged() {}\n",
+		"p/a_test.go":               "package p_test\n\nfunc Helper() {}\n",
+		"p/" + runtime.GOOS + ".go": "package p\n\nfunc Native() {}\n",
+	})
+	if err != nil {
+		t.Fatalf("Parse 返回错误：%v", err)
+	}
+	if pkg.Name != "p" {
+		t.Errorf("Name = %q，期望 p（//go:build ignore 的 package main 不参与比较）", pkg.Name)
+	}
+
+	var names []string
+	for name := range pkg.Objects {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"A", "Native"}; !slices.Equal(names, want) {
+		t.Errorf("导出标识符为 %q，期望 %q", names, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"包名不一致", map[string]string{"p/a.go": "package p\n", "p/b.go": "package q\n"}, "包名"},
+		{"语法错误", map[string]string{"p/a.go": "package p\n\nfunc {\n"}, "解析 p/a.go 失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse 返回错误 %v，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package apidiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change 是一个导出标识符在两个版本之间的不兼容变化。
+//
+// - Old: 旧版本中的标识符，接口新增方法时为空
+// - New: 新版本中的标识符，标识符被删除时为空
+// - Message: 一句话说明变化，例如 "函数 Parse 的签名由 (string) error 变为 (string, bool) error"
+type Change struct {
+	Name    string
+	Old     *Object
+	New     *Object
+	Message string
+}
+
+// Compare 比较 before 与 after 两个版本的导出 API，返回不兼容的变化：删除导出标识符、修改签名或类型、
+// 改变标识符的种类、把值接收者改为指针接收者，以及向包外可实现的接口添加方法。
+// 类型被删除或改变了种类时，不再单独报告它的字段与方法。结果按名称排序。
+//
+// 比较只基于语法，不做类型检查：例如把参数类型改为等价的类型别名也会被报告。
+func Compare(before, after *Package) []Change {
+	var changes []Change
+	add := func(name string, old, cur *Object, format string, args ...any) {
+		changes = append(changes, Change{Name: name, Old: old, New: cur, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// broken 记录已经报告为删除或改变种类的类型，它们的成员不再重复报告
+	broken := make(map[string]bool)
+	for name, old := range before.Objects {
+		if old.Kind != "type" {
+			continue
+		}
+		if cur, ok := after.Objects[name]; !ok || cur.Sig != old.Sig && kindOf(cur.Sig) != kindOf(old.Sig) {
+			broken[name] = true
+		}
+	}
+
+	for name, old := range before.Objects {
+		owner, _, member := strings.Cut(name, ".")
+		if member && broken[owner] {
+			continue
+		}
+		cur, ok := after.Objects[name]
+		if !ok {
+			add(name, &old, nil, "删除了导出的%s %s", kindName(old.Kind), name)
+			continue
+		}
+		switch {
+		case cur.Kind != old.Kind:
+			add(name, &old, &cur, "%s 由%s变为%s", name, kindName(old.Kind), kindName(cur.Kind))
+		case old.Sig != cur.Sig && (old.Sig != "" && cur.Sig != "" || old.Kind == "type"):
+			add(name, &old, &cur, "%s %s 的%s由 %s 变为 %s", kindName(old.Kind), name, sigName(old.Kind), orNone(old.Sig), orNone(cur.Sig))
+		case old.Kind == "method" && !old.PtrRecv && cur.PtrRecv:
+			add(name, &old, &cur, "方法 %s 的接收者由值改为指针，%s 类型的值不再拥有该方法", name, owner)
+		}
+	}
+
+	for name, cur := range after.Objects {
+		owner, _, member := strings.Cut(name, ".")
+		if _, ok := before.Objects[name]; ok || !member || broken[owner] || !before.ifaces[owner] {
+			continue
+		}
+		if cur.Kind == "method" || cur.Kind == "embed" {
+			add(name, nil, &cur, "接口 %s 新增了方法 %s，包外的实现将无法再满足该接口", owner, strings.TrimPrefix(name, owner+"."))
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// kindOf 返回类型签名的种类：struct、interface 或其他（空字符串），用于判断类型是否被整体替换。
+func kindOf(sig string) string {
+	sig = strings.TrimPrefix(sig, "= ")
+	if i := strings.LastIndex(sig, "]"); strings.HasPrefix(sig, "[") && i >= 0 {
+		sig = sig[i+1:]
+	}
+	if sig == "struct" || sig == "interface" {
+		return sig
+	}
+	return ""
+}
+
+// kindName 返回标识符种类的中文名称。
+func kindName(kind string) string {
+	switch kind {
+	case "func":
+		return "函数"
+	case "method":
+		return "方法"
+	case "type":
+		return "类型"
+	case "field":
+		return "字段"
+	case "const":
+		return "常量"
+	case "var":
+		return "变量"
+	case "embed":
+		return "嵌入接口"
+	default:
+		return kind
+	}
+}
+
+// sigName 返回比较内容的中文名称：函数与方法比较签名，其余比较类型。
+func sigName(kind string) string {
+	if kind == "func" || kind == "method" {
+		return "签名"
+	}
+	return "类型"
+}
+
+// orNone 在 s 为空时返回 "（无类型）"。
+func orNone(s string) string {
+	if s == "" {
+		return "（无类型）"
+	}
+	return s
+}
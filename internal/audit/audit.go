@@ -0,0 +1,143 @@
+// Package audit 把每次发送给 LLM 的请求以 JSON Lines 追加写入审计日志，
+// 用于证明哪些代码在什么时间被发送到了哪个接口。
+//
+// Provider 包装最内层的 ai.LLMProvider，记录的是经过外发策略与脱敏之后实际发出的内容；
+// 被拒绝发送的请求不会出现在日志中。每次请求在发出前先写一条记录，写入失败时不发送。
+package audit
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// defaultFile 是未指定路径时审计日志在状态目录中的文件名。
+const defaultFile = "audit.jsonl"
+
+// 审计记录的阶段：每次请求在发出之前写一条 StageSending，收到回复或失败后再写一条 StageCompleted，
+// 两条记录的 ID 相同。只有 StageSending 而没有对应 StageCompleted 的请求可能已经发出，但进程在完成前退出了。
+const (
+	StageSending   = "sending"
+	StageCompleted = "completed"
+)
+
+// Entry 是审计日志中的一行。
+//
+// - ID: 同一次请求的 sending 与 completed 记录共用的随机标识
+// - RequestHash: 发送的消息列表（JSON）的 SHA-256，不记录完整内容时也能核对某段内容是否发送过
+// - Messages: 仅在 sending 记录中、且开启 include_content 时记录
+// - Response: 仅在 completed 记录中、且开启 include_content 时记录
+// - Usage / Error: 仅在 completed 记录中，Error 为请求已发出但失败时的错误信息
+type Entry struct {
+	ID          string       `json:"id"`
+	Stage       string       `json:"stage"`
+	Time        time.Time    `json:"time"`
+	Repository  string       `json:"repository"`
+	Files       []string     `json:"files,omitempty"`
+	Provider    string       `json:"provider"`
+	Model       string       `json:"model"`
+	BaseURL     string       `json:"base_url"`
+	RequestHash string       `json:"request_hash"`
+	Messages    []ai.Message `json:"messages,omitempty"`
+	Response    string       `json:"response,omitempty"`
+	Usage       *ai.Usage    `json:"usage,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Logger 以只追加的方式写入审计日志。
+type Logger struct {
+	path           string
+	repo           string
+	includeContent bool
+
+	mu sync.Mutex
+}
+
+// Open 打开审计日志。path 为空时使用 <git-common-dir>/review-go/audit.jsonl。
+//
+// 打开时会确认文件可写：审计日志不可用时应当拒绝运行，而不是在不记录的情况下发送代码。
+func Open(path string, includeContent bool) (*Logger, error) {
+	repo, err := gitops.GetRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		dir, err := gitops.GetStateDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, defaultFile)
+	}
+
+	l := &Logger{path: path, repo: repo, includeContent: includeContent}
+	f, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return l, nil
+}
+
+// open 以追加模式打开日志文件。日志可能包含代码，因此只对当前用户可读写。
+func (l *Logger) open() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败：%w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志 %s 失败：%w", l.path, err)
+	}
+	return f, nil
+}
+
+// Log 追加一条记录。每条记录以一次 write 写入，同时运行的多个进程不会交错写出半行。
+func (l *Logger) Log(e Entry) error {
+	e.Repository = l.repo
+	if !l.includeContent {
+		e.Messages = nil
+		e.Response = ""
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := l.open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("写入审计日志 %s 失败：%w", l.path, err)
+	}
+	return nil
+}
+
+// newID 返回一个随机的请求标识。
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RequestHash 计算消息列表的 SHA-256。
+func RequestHash(messages []ai.Message) string {
+	data, _ := json.Marshal(messages)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+)
+
+// Provider 包装一个 ai.LLMProvider，为经过它的每次请求写一条审计记录。
+type Provider struct {
+	ai.Middleware
+	logger *Logger
+}
+
+// Wrap 用 logger 包装 provider；logger 为 nil 时直接返回 provider。
+func Wrap(provider ai.LLMProvider, logger *Logger) ai.LLMProvider {
+	if logger == nil {
+		return provider
+	}
+	p := &Provider{logger: logger}
+	p.Middleware = ai.Middleware{Next: provider, SendFunc: p.send}
+	return p
+}
+
+// send 是嵌入的 ai.Middleware 的 SendFunc：先写一条 sending 记录再转发请求，完成后写一条 completed 记录。
+//
+// sending 记录写入失败时不发送请求；completed 记录写入失败时丢弃回复并返回错误：
+// 无法证明发送过什么的结果不应被使用。
+func (p *Provider) send(req ai.Request) (string, ai.Usage, error) {
+	id := p.Identity()
+	entry := Entry{
+		ID:          newID(),
+		Stage:       StageSending,
+		Time:        time.Now(),
+		Files:       req.Files,
+		Provider:    id.Provider,
+		Model:       id.Model,
+		BaseURL:     id.BaseURL,
+		RequestHash: RequestHash(req.Messages),
+		Messages:    req.Messages,
+	}
+	if err := p.logger.Log(entry); err != nil {
+		return "", ai.Usage{}, fmt.Errorf("记录审计日志失败，未发送请求：%w", err)
+	}
+
+	reply, usage, err := ai.Send(p.Next, req)
+
+	entry.Stage = StageCompleted
+	entry.Time = time.Now()
+	entry.Messages = nil
+	entry.Response = reply
+	entry.Usage = &usage
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := p.logger.Log(entry); logErr != nil {
+		return "", usage, fmt.Errorf("记录审计日志失败：%w", logErr)
+	}
+	return reply, usage, err
+}
@@ -0,0 +1,220 @@
+// Package cache 是一个按内容寻址的本地文件缓存，用于保存 LLM 的回复，避免对相同输入重复付费。
+//
+// 每个条目保存为 <dir>/<key 前两位>/<key>，读取命中时更新文件的修改时间，
+// 总大小超过上限时按修改时间从旧到新淘汰（近似 LRU）。
+package cache
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// dirName 是缓存在状态目录中的子目录名。
+const dirName = "cache"
+
+// Cache 是一个有大小上限的本地缓存。零值不可用，请使用 Open 或 New 创建。
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mu sync.Mutex
+}
+
+// Stats 是缓存的统计信息。
+type Stats struct {
+	Dir     string
+	Entries int
+	Size    int64
+	MaxSize int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Open 打开当前仓库的缓存（<git-common-dir>/review-go/cache）。maxSize 为字节数上限，<= 0 表示不限制。
+func Open(maxSize int64) (*Cache, error) {
+	dir, err := gitops.GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return New(filepath.Join(dir, dirName), maxSize)
+}
+
+// New 在 dir 下创建缓存，目录不存在时自动创建。
+func New(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建缓存目录 %s 失败：%w", dir, err)
+	}
+	return &Cache{dir: dir, maxSize: maxSize}, nil
+}
+
+// Key 把若干部分组合并计算为缓存 key（SHA-256 十六进制）。各部分之间以 NUL 分隔，避免拼接歧义。
+func Key(parts ...string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(parts, "\x00"))))
+}
+
+// path 返回 key 对应的文件路径。
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Get 读取 key 对应的内容。命中时刷新条目的修改时间，使其在淘汰时排在后面。
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.path(key)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+	return data, true
+}
+
+// Put 写入 key 对应的内容，写入后如超过大小上限则淘汰最久未使用的条目。
+//
+// 先写临时文件再重命名，避免并发读到写了一半的条目。
+func (c *Cache) Put(key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("创建缓存目录失败：%w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), key+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("写入缓存失败：%w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败：%w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败：%w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败：%w", err)
+	}
+
+	return c.evict()
+}
+
+// entry 是缓存目录中的一个条目。
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries 列出缓存中的全部条目（跳过写入中的临时文件）。
+func (c *Cache) entries() ([]entry, error) {
+	var list []entry
+	err := filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.Contains(d.Name(), ".tmp-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		list = append(list, entry{path: p, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return list, err
+}
+
+// evict 在总大小超过上限时，按修改时间从旧到新删除条目，直到回到上限以内。
+func (c *Cache) evict() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	list, err := c.entries()
+	if err != nil {
+		return fmt.Errorf("读取缓存目录失败：%w", err)
+	}
+
+	var total int64
+	for _, e := range list {
+		total += e.size
+	}
+	if total <= c.maxSize {
+		return nil
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].modTime.Before(list[j].modTime) })
+	for _, e := range list {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err == nil {
+			total -= e.size
+		}
+	}
+	return nil
+}
+
+// Stats 返回缓存的条目数、总大小与新旧时间范围。
+func (c *Cache) Stats() (Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Stats{Dir: c.dir, MaxSize: c.maxSize}
+	list, err := c.entries()
+	if err != nil {
+		return s, fmt.Errorf("读取缓存目录失败：%w", err)
+	}
+
+	for _, e := range list {
+		s.Entries++
+		s.Size += e.size
+		if s.Oldest.IsZero() || e.modTime.Before(s.Oldest) {
+			s.Oldest = e.modTime
+		}
+		if e.modTime.After(s.Newest) {
+			s.Newest = e.modTime
+		}
+	}
+	return s, nil
+}
+
+// Clear 删除全部缓存条目，返回删除的条目数。
+func (c *Cache) Clear() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list, err := c.entries()
+	if err != nil {
+		return 0, fmt.Errorf("读取缓存目录失败：%w", err)
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		return 0, fmt.Errorf("清空缓存目录 %s 失败：%w", c.dir, err)
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return 0, fmt.Errorf("创建缓存目录 %s 失败：%w", c.dir, err)
+	}
+	return len(list), nil
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -30,6 +32,184 @@ type ProviderConfig struct {
 	Model   string `mapstructure:"model" yaml:"model"`
 }
 
+// AnalysisConfig 控制在调用 LLM 之前运行的静态分析工具。
+//
+// YAML 结构示例：
+//
+//	analysis:
+//	  go_vet: true         # 默认开启
+//	  staticcheck: true    # 默认开启，仅在 PATH 中存在时运行
+//	  golangci_lint: false # 默认关闭，开启后仅在 PATH 中存在时运行
+type AnalysisConfig struct {
+	GoVet        bool `mapstructure:"go_vet" yaml:"go_vet"`
+	Staticcheck  bool `mapstructure:"staticcheck" yaml:"staticcheck"`
+	GolangciLint bool `mapstructure:"golangci_lint" yaml:"golangci_lint"`
+}
+
+// FixConfig 控制修复模式（--fix）下补丁的验证方式。
+//
+// YAML 结构示例：
+//
+//	fix:
+//	  verify: true        # 默认开启：在临时 worktree 中应用补丁并执行 go build ./...
+//	  verify_tests: false # 编译通过后再对补丁所在的包执行 go test
+//	  drop_failed: false  # 丢弃验证失败的补丁，而不是仅做标记
+type FixConfig struct {
+	Verify      bool `mapstructure:"verify" yaml:"verify"`
+	VerifyTests bool `mapstructure:"verify_tests" yaml:"verify_tests"`
+	DropFailed  bool `mapstructure:"drop_failed" yaml:"drop_failed"`
+}
+
+// CacheConfig 控制 LLM 回复缓存。
+//
+// YAML 结构示例：
+//
+//	cache:
+//	  enabled: true    # 默认开启，也可以用 --no-cache 临时跳过
+//	  max_size_mb: 50  # 缓存总大小上限，超出后淘汰最久未使用的条目
+type CacheConfig struct {
+	Enabled   bool `mapstructure:"enabled" yaml:"enabled"`
+	MaxSizeMB int  `mapstructure:"max_size_mb" yaml:"max_size_mb"`
+}
+
+// ModelPrice 是某个模型每百万 token 的价格。
+type ModelPrice struct {
+	Model      string  `mapstructure:"model" yaml:"model"`
+	Prompt     float64 `mapstructure:"prompt" yaml:"prompt"`
+	Completion float64 `mapstructure:"completion" yaml:"completion"`
+}
+
+// BudgetConfig 是 LLM 调用的预算上限，达到任意一项后不再发送请求。0 表示不限制。
+type BudgetConfig struct {
+	RunCost   float64 `mapstructure:"run_cost" yaml:"run_cost"`
+	DayCost   float64 `mapstructure:"day_cost" yaml:"day_cost"`
+	RunTokens int     `mapstructure:"run_tokens" yaml:"run_tokens"`
+	DayTokens int     `mapstructure:"day_tokens" yaml:"day_tokens"`
+}
+
+// UsageConfig 控制 token 用量与费用的统计。
+//
+// 模型名中常带有 "."（如 qwen2.5-coder），因此价格使用列表而不是以模型名为 key 的映射。
+//
+// YAML 结构示例：
+//
+//	usage:
+//	  currency: "$"          # 费用显示时使用的货币符号
+//	  prices:                # 每百万 token 的价格，未列出的模型只统计 token 数
+//	    - model: "gpt-4o"
+//	      prompt: 2.5
+//	      completion: 10
+//	  budget:
+//	    run_cost: 0.5        # 单次运行的费用上限
+//	    day_cost: 5          # 当天（当前仓库）的费用上限
+//	    run_tokens: 0        # 单次运行的 token 上限
+//	    day_tokens: 0        # 当天（当前仓库）的 token 上限
+type UsageConfig struct {
+	Currency string       `mapstructure:"currency" yaml:"currency"`
+	Prices   []ModelPrice `mapstructure:"prices" yaml:"prices"`
+	Budget   BudgetConfig `mapstructure:"budget" yaml:"budget"`
+}
+
+// RedactionPattern 是一条自定义的密钥匹配规则。正则中有捕获组时只替换第一个捕获组，否则替换整个匹配。
+type RedactionPattern struct {
+	Name  string `mapstructure:"name" yaml:"name"`
+	Regex string `mapstructure:"regex" yaml:"regex"`
+}
+
+// RedactionConfig 控制发送给 LLM 之前的密钥脱敏。
+//
+// YAML 结构示例：
+//
+//	redaction:
+//	  mode: "redact"   # redact（默认）：替换为占位符；block：发现密钥时拒绝发送；off：关闭
+//	  entropy: true    # 默认开启：检测高熵字符串
+//	  patterns:        # 在内置规则之外追加的规则
+//	    - name: "internal-token"
+//	      regex: "itk_[0-9a-f]{32}"
+type RedactionConfig struct {
+	Mode     string             `mapstructure:"mode" yaml:"mode"`
+	Entropy  bool               `mapstructure:"entropy" yaml:"entropy"`
+	Patterns []RedactionPattern `mapstructure:"patterns" yaml:"patterns"`
+}
+
+// EgressRule 把一组路径 glob 映射到允许接收这些文件的提供商。
+// Providers 为 ["never"] 时这些文件不发送给任何提供商。
+type EgressRule struct {
+	Paths     []string `mapstructure:"paths" yaml:"paths"`
+	Providers []string `mapstructure:"providers" yaml:"providers"`
+}
+
+// EgressConfig 是按路径限制代码外发的策略，按顺序匹配，第一条匹配的规则生效；
+// 没有匹配任何规则的文件可以发送给任意提供商。
+//
+// YAML 结构示例：
+//
+//	egress:
+//	  rules:
+//	    - paths: ["internal/billing/**"]
+//	      providers: ["onprem"]       # 只允许发送给名为 onprem 的提供商
+//	    - paths: ["secrets/**", "**/*_secret.go"]
+//	      providers: ["never"]        # 不发送给任何提供商
+type EgressConfig struct {
+	Rules []EgressRule `mapstructure:"rules" yaml:"rules"`
+}
+
+// AuditConfig 控制审计日志：记录每次发送给 LLM 的请求。
+//
+// YAML 结构示例：
+//
+//	audit:
+//	  enabled: false           # 默认关闭
+//	  path: ""                 # 日志文件路径，留空时为 <git-common-dir>/review-go/audit.jsonl
+//	  include_content: false   # 是否记录完整的 prompt 与回复（默认只记录哈希与元数据）
+type AuditConfig struct {
+	Enabled        bool   `mapstructure:"enabled" yaml:"enabled"`
+	Path           string `mapstructure:"path" yaml:"path"`
+	IncludeContent bool   `mapstructure:"include_content" yaml:"include_content"`
+}
+
+// CommitConfig 控制 review-go commit-msg 生成的提交信息。
+//
+// YAML 结构示例：
+//
+//	commit:
+//	  language: "English"      # 提交信息使用的语言，如 English、中文
+//	  style: "conventional"    # conventional（默认）：Conventional Commits；plain：不带 type 前缀的一句话标题
+//	  body: true               # 默认开启：在标题之后生成说明改动内容与原因的正文
+//	  max_subject_length: 72   # 标题的最大长度
+//	  instructions: ""         # 追加给模型的要求，例如团队约定的 scope 列表
+type CommitConfig struct {
+	Language         string `mapstructure:"language" yaml:"language"`
+	Style            string `mapstructure:"style" yaml:"style"`
+	Body             bool   `mapstructure:"body" yaml:"body"`
+	MaxSubjectLength int    `mapstructure:"max_subject_length" yaml:"max_subject_length"`
+	Instructions     string `mapstructure:"instructions" yaml:"instructions"`
+}
+
+// PRConfig 控制 review-go pr-desc 生成的 PR 描述。
+//
+// YAML 结构示例：
+//
+//	pr:
+//	  language: "English"   # PR 描述使用的语言
+//	  template: ""          # Markdown 模板文件，相对路径相对于仓库根目录；留空时使用内置模板
+//	  instructions: ""      # 追加给模型的要求
+type PRConfig struct {
+	Language     string `mapstructure:"language" yaml:"language"`
+	Template     string `mapstructure:"template" yaml:"template"`
+	Instructions string `mapstructure:"instructions" yaml:"instructions"`
+}
+
+// DepsConfig 控制 go.mod / go.sum 变更的依赖检查。
+//
+// YAML 结构示例：
+//
+//	deps:
+//	  vuln_db: "/opt/vulndb"   # 离线漏洞库目录或 file:// URL（govulncheck 格式），留空时使用环境变量 GOVULNDB
+type DepsConfig struct {
+	VulnDB string `mapstructure:"vuln_db" yaml:"vuln_db"`
+}
+
 // Config 保存从配置文件加载的全局配置。
 //
 // 期望的配置结构示例（~/.review-go.yaml）：
@@ -72,11 +252,51 @@ type Config struct {
 	APIKey  string `mapstructure:"api_key" yaml:"api_key"`
 	BaseURL string `mapstructure:"base_url" yaml:"base_url"`
 	Model   string `mapstructure:"model" yaml:"model"`
+
+	// Analysis 是审查前静态分析的开关，与提供商无关。
+	Analysis AnalysisConfig `mapstructure:"analysis" yaml:"analysis"`
+
+	// Fix 是修复模式下补丁验证的配置。
+	Fix FixConfig `mapstructure:"fix" yaml:"fix"`
+
+	// Cache 是 LLM 回复缓存的配置。
+	Cache CacheConfig `mapstructure:"cache" yaml:"cache"`
+
+	// Usage 是 token 用量、模型价格与预算的配置。
+	Usage UsageConfig `mapstructure:"usage" yaml:"usage"`
+
+	// Redaction 是发送前密钥脱敏的配置。
+	Redaction RedactionConfig `mapstructure:"redaction" yaml:"redaction"`
+
+	// Egress 是按路径限制代码发送给哪些提供商的策略。
+	Egress EgressConfig `mapstructure:"egress" yaml:"egress"`
+
+	// Audit 是审计日志的配置。
+	Audit AuditConfig `mapstructure:"audit" yaml:"audit"`
+
+	// Commit 是生成提交信息的语言与风格。
+	Commit CommitConfig `mapstructure:"commit" yaml:"commit"`
+
+	// PR 是生成 PR 描述的语言与模板。
+	PR PRConfig `mapstructure:"pr" yaml:"pr"`
+
+	// Deps 是依赖变更检查的配置。
+	Deps DepsConfig `mapstructure:"deps" yaml:"deps"`
 }
 
-// Load 从 ~/.review-go.yaml 读取配置。
-//
+// Load 从 ~/.review-go.yaml 读取配置，要求当前提供商配置了 api_key。
 func Load() (*Config, error) {
+	return load(true)
+}
+
+// LoadWithoutKey 与 Load 相同，但不要求 api_key，配置文件不存在时返回默认配置。
+// 用于试运行等不会访问 LLM 的场景。
+func LoadWithoutKey() (*Config, error) {
+	return load(false)
+}
+
+// load 读取配置；requireKey 为 false 时跳过与 api_key、当前提供商相关的校验。
+func load(requireKey bool) (*Config, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("get user home dir: %w", err)
@@ -93,9 +313,27 @@ func Load() (*Config, error) {
 	// 实际必填校验在后面进行。
 	v.SetDefault("provider", "")
 	v.SetDefault("api_key", "")
+	v.SetDefault("analysis.go_vet", true)
+	v.SetDefault("analysis.staticcheck", true)
+	v.SetDefault("analysis.golangci_lint", false)
+	v.SetDefault("fix.verify", true)
+	v.SetDefault("fix.verify_tests", false)
+	v.SetDefault("fix.drop_failed", false)
+	v.SetDefault("cache.enabled", true)
+	v.SetDefault("cache.max_size_mb", 50)
+	v.SetDefault("usage.currency", "$")
+	v.SetDefault("redaction.mode", "redact")
+	v.SetDefault("redaction.entropy", true)
+	v.SetDefault("commit.language", "English")
+	v.SetDefault("commit.style", "conventional")
+	v.SetDefault("commit.body", true)
+	v.SetDefault("commit.max_subject_length", 72)
+	v.SetDefault("pr.language", "English")
 
 	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("read config file %s: %w", configPath, err)
+		if requireKey || !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read config file %s: %w", configPath, err)
+		}
 	}
 
 	var cfg Config
@@ -105,6 +343,16 @@ func Load() (*Config, error) {
 
 	// 如果配置中定义了 providers，则走多提供商逻辑。
 	if len(cfg.Providers) > 0 {
+		if !requireKey {
+			// 不要求 api_key 时，当前提供商缺失也不视为错误，只在存在时扁平化
+			if providerCfg, ok := cfg.Providers[cfg.Provider]; ok {
+				cfg.APIKey = providerCfg.APIKey
+				cfg.BaseURL = providerCfg.BaseURL
+				cfg.Model = providerCfg.Model
+			}
+			return &cfg, nil
+		}
+
 		if cfg.Provider == "" {
 			return nil, fmt.Errorf("provider is empty in %s", configPath)
 		}
@@ -127,7 +375,7 @@ func Load() (*Config, error) {
 	}
 
 	// 兼容旧版：没有 providers 字段时，仍然要求存在顶层 api_key。
-	if cfg.APIKey == "" {
+	if requireKey && cfg.APIKey == "" {
 		return nil, fmt.Errorf("api_key is empty in %s", configPath)
 	}
 
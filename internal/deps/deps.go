@@ -0,0 +1,354 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Files 是某个版本的 go.mod 与 go.sum 的内容，文件不存在时为空。
+type Files struct {
+	Mod string
+	Sum string
+}
+
+// Options 控制依赖检查。
+//
+// - VulnDB: 离线漏洞库的目录或 file:// URL（govulncheck 的格式），为空时使用环境变量 GOVULNDB 中的 file:// 地址，都没有时跳过漏洞检查
+// - ModCache: 用于识别许可证的模块缓存目录，为空时使用 DefaultModCache
+type Options struct {
+	VulnDB   string
+	ModCache string
+}
+
+// Change 是一个依赖在两个版本的 go.mod 之间的变化。
+//
+// - Old / New: 旧版本与新版本，新增的依赖 Old 为空，移除的依赖 New 为空
+// - OldPath: 改用新的主版本路径（例如 a → a/v2）时为原来的模块路径
+// - Line: 该依赖在新 go.mod 中的行号，移除的依赖为 0
+type Change struct {
+	Path     string
+	OldPath  string
+	Old      string
+	New      string
+	Indirect bool
+	Line     int
+}
+
+// Major 报告是否跨越了主版本：主版本号不同，或改用了新的主版本路径。
+func (c Change) Major() bool {
+	if c.Old == "" || c.New == "" {
+		return false
+	}
+	return c.OldPath != "" || semver.Major(c.Old) != semver.Major(c.New)
+}
+
+// Kind 返回变化的类型：新增、移除、大版本升级、升级、降级或变更。
+func (c Change) Kind() string {
+	switch {
+	case c.Old == "":
+		return "新增"
+	case c.New == "":
+		return "移除"
+	case c.Major() && semver.Compare(c.New, c.Old) > 0:
+		return "大版本升级"
+	case semver.Compare(c.New, c.Old) > 0:
+		return "升级"
+	case semver.Compare(c.New, c.Old) < 0:
+		return "降级"
+	default:
+		return "变更"
+	}
+}
+
+// LicenseChange 是模块缓存中识别出的许可证变化：Old 为旧版本的许可证，新增的依赖为空。
+type LicenseChange struct {
+	Module  string
+	Version string
+	Old     string
+	New     string
+	Line    int
+}
+
+// SumMismatch 是 go.sum 中同一个模块版本的校验和发生了变化，可能意味着依赖被篡改。
+type SumMismatch struct {
+	Module  string
+	Version string
+	Old     string
+	New     string
+}
+
+// Report 是依赖检查的结果，不依赖 LLM。
+//
+// - Changes: 新增、移除与版本变化的依赖，按新 go.mod 中的顺序排列，移除的依赖在最后
+// - Replaces: 新增或修改的 replace 指令
+// - Vulns: 离线漏洞库中影响新增或升级后版本的漏洞
+// - Licenses: 新增依赖的许可证，以及升级前后许可证不同的依赖
+// - Sums: go.sum 中校验和发生变化的模块版本
+// - Notes: 检查中跳过或失败的部分，例如没有配置漏洞库
+type Report struct {
+	Changes  []Change
+	Replaces []Replace
+	Vulns    []Vuln
+	Licenses []LicenseChange
+	Sums     []SumMismatch
+	Notes    []string
+}
+
+// Compare 比较旧版本与新版本的 go.mod、go.sum，报告依赖的变化、replace 指令、已知漏洞与许可证变化。
+// go.mod 无法解析、漏洞库或模块缓存不可用时只跳过对应的检查，原因记录在 Notes 中。
+func Compare(before, after Files, opts Options) *Report {
+	om, oerr := ParseModFile(before.Mod)
+	nm, nerr := ParseModFile(after.Mod)
+	rep := &Report{
+		Changes:  diffRequires(om.Requires, nm.Requires),
+		Replaces: diffReplaces(om.Replaces, nm.Replaces),
+		Sums:     diffSums(parseSumFile(before.Sum), parseSumFile(after.Sum)),
+	}
+	if oerr != nil {
+		rep.Notes = append(rep.Notes, fmt.Sprintf("解析旧版本的 go.mod 失败：%v", oerr))
+	}
+	if nerr != nil {
+		rep.Notes = append(rep.Notes, fmt.Sprintf("解析新版本的 go.mod 失败：%v", nerr))
+	}
+
+	// 只检查新增或升级后的版本：其余依赖没有变化，不属于本次变更引入的风险
+	changed := make(map[string]string)
+	for _, c := range rep.Changes {
+		if c.New != "" {
+			changed[c.Path] = c.New
+		}
+	}
+
+	db := opts.VulnDB
+	if db == "" && strings.HasPrefix(os.Getenv("GOVULNDB"), "file://") {
+		db = os.Getenv("GOVULNDB")
+	}
+	switch {
+	case len(changed) == 0:
+	case db == "":
+		rep.Notes = append(rep.Notes, "未配置离线漏洞库（deps.vuln_db 或 GOVULNDB），跳过漏洞检查")
+	default:
+		vulns, err := checkVulns(db, changed)
+		if err != nil {
+			rep.Notes = append(rep.Notes, fmt.Sprintf("漏洞检查失败：%v", err))
+		}
+		rep.Vulns = vulns
+	}
+
+	cache := opts.ModCache
+	if cache == "" {
+		cache = DefaultModCache()
+	}
+	rep.Licenses, rep.Notes = diffLicenses(cache, rep.Changes, rep.Notes)
+	return rep
+}
+
+// diffRequires 比较 before 与 after 两个版本的 require 列表。新增的路径与移除的路径只差主版本后缀时合并为一次大版本升级。
+func diffRequires(before, after []Require) []Change {
+	oldByPath := make(map[string]Require, len(before))
+	for _, r := range before {
+		oldByPath[r.Path] = r
+	}
+	newByPath := make(map[string]bool, len(after))
+
+	var changes []Change
+	for _, r := range after {
+		newByPath[r.Path] = true
+		prev, ok := oldByPath[r.Path]
+		if ok && prev.Version == r.Version {
+			continue
+		}
+		c := Change{Path: r.Path, New: r.Version, Indirect: r.Indirect, Line: r.Line}
+		if ok {
+			c.Old = prev.Version
+		}
+		changes = append(changes, c)
+	}
+
+	var removed []Require
+	for _, r := range before {
+		if !newByPath[r.Path] {
+			removed = append(removed, r)
+		}
+	}
+
+	for _, r := range removed {
+		prefix, _, _ := module.SplitPathVersion(r.Path)
+		merged := false
+		for i, c := range changes {
+			if p, _, _ := module.SplitPathVersion(c.Path); c.Old == "" && p == prefix {
+				changes[i].OldPath, changes[i].Old = r.Path, r.Version
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			changes = append(changes, Change{Path: r.Path, Old: r.Version, Indirect: r.Indirect})
+		}
+	}
+	return changes
+}
+
+// diffReplaces 返回 after 中新增或修改了目标的 replace 指令。
+func diffReplaces(before, after []Replace) []Replace {
+	prev := make(map[string]Replace, len(before))
+	for _, r := range before {
+		prev[r.Old+" "+r.OldVersion] = r
+	}
+
+	var changed []Replace
+	for _, r := range after {
+		p, ok := prev[r.Old+" "+r.OldVersion]
+		if ok && p.New == r.New && p.NewVersion == r.NewVersion {
+			continue
+		}
+		changed = append(changed, r)
+	}
+	return changed
+}
+
+// diffSums 返回在两个版本的 go.sum 中都出现、但校验和不同的模块版本。
+func diffSums(before, after map[string]string) []SumMismatch {
+	var mismatches []SumMismatch
+	for key, sum := range after {
+		prev, ok := before[key]
+		if !ok || prev == sum {
+			continue
+		}
+		module, version, _ := strings.Cut(key, " ")
+		mismatches = append(mismatches, SumMismatch{Module: module, Version: version, Old: prev, New: sum})
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		if mismatches[i].Module != mismatches[j].Module {
+			return mismatches[i].Module < mismatches[j].Module
+		}
+		return mismatches[i].Version < mismatches[j].Version
+	})
+	return mismatches
+}
+
+// diffLicenses 在模块缓存中识别新增与升级的依赖的许可证，返回新增依赖的许可证与发生变化的许可证；
+// 无法比较的情况追加到 notes 中。
+func diffLicenses(cache string, changes []Change, notes []string) ([]LicenseChange, []string) {
+	if cache == "" {
+		return nil, append(notes, "找不到模块缓存目录，跳过许可证检查")
+	}
+
+	var licenses []LicenseChange
+	missing := 0
+	for _, c := range changes {
+		if c.New == "" {
+			continue
+		}
+		lic := detectLicense(cache, c.Path, c.New)
+		if lic == "" {
+			missing++
+			continue
+		}
+		if c.Old == "" {
+			licenses = append(licenses, LicenseChange{Module: c.Path, Version: c.New, New: lic, Line: c.Line})
+			continue
+		}
+
+		oldPath := c.Path
+		if c.OldPath != "" {
+			oldPath = c.OldPath
+		}
+		prev := detectLicense(cache, oldPath, c.Old)
+		if prev == "" {
+			missing++
+			continue
+		}
+		if prev != lic {
+			licenses = append(licenses, LicenseChange{Module: c.Path, Version: c.New, Old: prev, New: lic, Line: c.Line})
+		}
+	}
+	if missing > 0 {
+		notes = append(notes, fmt.Sprintf("%d 个模块版本不在模块缓存中，未能检查其许可证（可先执行 go mod download）", missing))
+	}
+	return licenses, notes
+}
+
+// Markdown 把检查结果渲染为 Markdown，用于在审查报告中展示，也作为提示词的一部分发送给 LLM。
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## 依赖变更\n\n")
+	if len(r.Changes) == 0 {
+		b.WriteString("require 没有变化。\n")
+	} else {
+		b.WriteString("| 模块 | 变化 | 旧版本 | 新版本 |\n|---|---|---|---|\n")
+		for _, c := range r.Changes {
+			name := c.Path
+			if c.Indirect {
+				name += "（间接）"
+			}
+			old := orDash(c.Old)
+			if c.OldPath != "" {
+				old = fmt.Sprintf("%s（%s）", c.Old, c.OldPath)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", name, c.Kind(), old, orDash(c.New))
+		}
+	}
+
+	if len(r.Replaces) > 0 {
+		b.WriteString("\n### replace 指令\n\n")
+		for _, rp := range r.Replaces {
+			local := ""
+			if rp.Local() {
+				local = "，本地路径"
+			}
+			fmt.Fprintf(&b, "- `%s`（第 %d 行%s）\n", rp, rp.Line, local)
+		}
+	}
+
+	if len(r.Vulns) > 0 {
+		b.WriteString("\n### 已知漏洞\n\n")
+		for _, v := range r.Vulns {
+			id := v.ID
+			if len(v.Aliases) > 0 {
+				id += "（" + strings.Join(v.Aliases, "、") + "）"
+			}
+			fixed := "尚无修复版本"
+			if v.Fixed != "" {
+				fixed = "修复版本 " + v.Fixed
+			}
+			fmt.Fprintf(&b, "- **%s** %s@%s：%s；%s\n", id, v.Module, v.Version, v.Summary, fixed)
+		}
+	}
+
+	if len(r.Licenses) > 0 {
+		b.WriteString("\n### 许可证\n\n")
+		for _, l := range r.Licenses {
+			if l.Old == "" {
+				fmt.Fprintf(&b, "- %s@%s（新增）：%s\n", l.Module, l.Version, l.New)
+			} else {
+				fmt.Fprintf(&b, "- %s@%s：%s → %s\n", l.Module, l.Version, l.Old, l.New)
+			}
+		}
+	}
+
+	if len(r.Sums) > 0 {
+		b.WriteString("\n### go.sum 校验和变化\n\n")
+		for _, s := range r.Sums {
+			fmt.Fprintf(&b, "- %s %s：%s → %s\n", s.Module, s.Version, s.Old, s.New)
+		}
+	}
+
+	for _, n := range r.Notes {
+		fmt.Fprintf(&b, "\n_%s。_\n", n)
+	}
+	return b.String()
+}
+
+// orDash 在 s 为空时返回 "-"，用于表格中的空单元格。
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
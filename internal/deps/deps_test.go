@@ -0,0 +1,181 @@
+package deps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRequires(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []Require
+		after  []Require
+		want   []Change
+	}{
+		{
+			name:  "新增",
+			after: []Require{{Path: "a", Version: "v1.0.0", Line: 3}},
+			want:  []Change{{Path: "a", New: "v1.0.0", Line: 3}},
+		},
+		{
+			name:   "移除",
+			before: []Require{{Path: "a", Version: "v1.0.0", Indirect: true, Line: 3}},
+			want:   []Change{{Path: "a", Old: "v1.0.0", Indirect: true}},
+		},
+		{
+			name:   "升级",
+			before: []Require{{Path: "a", Version: "v1.0.0", Line: 3}},
+			after:  []Require{{Path: "a", Version: "v1.2.0", Line: 4}},
+			want:   []Change{{Path: "a", Old: "v1.0.0", New: "v1.2.0", Line: 4}},
+		},
+		{
+			name:   "降级",
+			before: []Require{{Path: "a", Version: "v1.2.0", Line: 3}},
+			after:  []Require{{Path: "a", Version: "v1.0.0", Line: 3}},
+			want:   []Change{{Path: "a", Old: "v1.2.0", New: "v1.0.0", Line: 3}},
+		},
+		{
+			name:   "版本不变",
+			before: []Require{{Path: "a", Version: "v1.0.0", Line: 3}},
+			after:  []Require{{Path: "a", Version: "v1.0.0", Line: 5}},
+		},
+		{
+			name:   "改用新的主版本路径",
+			before: []Require{{Path: "example.com/a", Version: "v1.5.0", Line: 3}, {Path: "b", Version: "v1.0.0", Line: 4}},
+			after:  []Require{{Path: "b", Version: "v1.0.0", Line: 3}, {Path: "example.com/a/v2", Version: "v2.0.0", Line: 4}},
+			want:   []Change{{Path: "example.com/a/v2", OldPath: "example.com/a", Old: "v1.5.0", New: "v2.0.0", Line: 4}},
+		},
+		{
+			name:   "gopkg.in 的主版本路径",
+			before: []Require{{Path: "gopkg.in/yaml.v2", Version: "v2.4.0", Line: 3}},
+			after:  []Require{{Path: "gopkg.in/yaml.v3", Version: "v3.0.1", Line: 3}},
+			want:   []Change{{Path: "gopkg.in/yaml.v3", OldPath: "gopkg.in/yaml.v2", Old: "v2.4.0", New: "v3.0.1", Line: 3}},
+		},
+		{
+			name:   "不同模块不合并",
+			before: []Require{{Path: "example.com/a", Version: "v1.0.0", Line: 3}},
+			after:  []Require{{Path: "example.com/b/v2", Version: "v2.0.0", Line: 3}},
+			want: []Change{
+				{Path: "example.com/b/v2", New: "v2.0.0", Line: 3},
+				{Path: "example.com/a", Old: "v1.0.0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffRequires(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffRequires = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeKind(t *testing.T) {
+	tests := []struct {
+		c     Change
+		kind  string
+		major bool
+	}{
+		{Change{Path: "a", New: "v1.0.0"}, "新增", false},
+		{Change{Path: "a", Old: "v1.0.0"}, "移除", false},
+		{Change{Path: "a", Old: "v1.0.0", New: "v1.2.0"}, "升级", false},
+		{Change{Path: "a", Old: "v1.2.0", New: "v1.0.0"}, "降级", false},
+		{Change{Path: "a/v2", OldPath: "a", Old: "v1.5.0", New: "v2.0.0"}, "大版本升级", true},
+		{Change{Path: "a", Old: "v0.9.0", New: "v1.0.0"}, "大版本升级", true},
+		{Change{Path: "a", Old: "v1.0.0", New: "v1.0.0+incompatible"}, "变更", false},
+	}
+	for _, tt := range tests {
+		if kind, major := tt.c.Kind(), tt.c.Major(); kind != tt.kind || major != tt.major {
+			t.Errorf("%+v: Kind() = %s, Major() = %v，期望 %s, %v", tt.c, kind, major, tt.kind, tt.major)
+		}
+	}
+}
+
+func TestDiffSums(t *testing.T) {
+	before := parseSumFile(`example.com/a v1.0.0 h1:aaa=
+example.com/a v1.0.0/go.mod h1:amod=
+example.com/b v1.0.0 h1:bbb=
+example.com/c v1.0.0 h1:ccc=
+`)
+	after := parseSumFile(`example.com/a v1.0.0 h1:aaa=
+example.com/a v1.0.0/go.mod h1:changed=
+example.com/b v1.1.0 h1:bbb2=
+example.com/c v1.0.0 h1:ccc2=
+example.com/d v1.0.0 h1:ddd=
+`)
+
+	want := []SumMismatch{
+		{Module: "example.com/a", Version: "v1.0.0/go.mod", Old: "h1:amod=", New: "h1:changed="},
+		{Module: "example.com/c", Version: "v1.0.0", Old: "h1:ccc=", New: "h1:ccc2="},
+	}
+	if got := diffSums(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSums = %+v，期望 %+v", got, want)
+	}
+	if got := diffSums(before, before); len(got) != 0 {
+		t.Errorf("diffSums 对相同的 go.sum 返回 %+v，期望为空", got)
+	}
+}
+
+func TestParseModFile(t *testing.T) {
+	mf, err := ParseModFile(`module example.com/m
+
+go 1.23
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.2.0 // indirect
+)
+
+require example.com/c/v2 v2.0.0
+
+replace example.com/a => ../a
+
+replace example.com/b v1.2.0 => example.com/fork/b v1.2.1
+`)
+	if err != nil {
+		t.Fatalf("ParseModFile 返回错误：%v", err)
+	}
+	if mf.Module != "example.com/m" {
+		t.Errorf("Module = %q，期望 example.com/m", mf.Module)
+	}
+
+	wantRequires := []Require{
+		{Path: "example.com/a", Version: "v1.0.0", Line: 6},
+		{Path: "example.com/b", Version: "v1.2.0", Indirect: true, Line: 7},
+		{Path: "example.com/c/v2", Version: "v2.0.0", Line: 10},
+	}
+	if !reflect.DeepEqual(mf.Requires, wantRequires) {
+		t.Errorf("Requires = %+v，期望 %+v", mf.Requires, wantRequires)
+	}
+
+	wantReplaces := []Replace{
+		{Old: "example.com/a", New: "../a", Line: 12},
+		{Old: "example.com/b", OldVersion: "v1.2.0", New: "example.com/fork/b", NewVersion: "v1.2.1", Line: 14},
+	}
+	if !reflect.DeepEqual(mf.Replaces, wantReplaces) {
+		t.Errorf("Replaces = %+v，期望 %+v", mf.Replaces, wantReplaces)
+	}
+	if !mf.Replaces[0].Local() || mf.Replaces[1].Local() {
+		t.Errorf("Local() = %v / %v，期望 true / false", mf.Replaces[0].Local(), mf.Replaces[1].Local())
+	}
+	if s := mf.Replaces[1].String(); s != "example.com/b v1.2.0 => example.com/fork/b v1.2.1" {
+		t.Errorf("String() = %q", s)
+	}
+
+	if mf, err := ParseModFile(""); err != nil || mf.Module != "" || len(mf.Requires) != 0 {
+		t.Errorf("ParseModFile(\"\") = %+v, %v，期望空的 ModFile", mf, err)
+	}
+	if _, err := ParseModFile("module example.com/m\n\nrequire (\n"); err == nil {
+		t.Error("ParseModFile 对语法错误没有返回错误")
+	}
+
+	// 无法识别的指令：返回错误，但仍宽松地解析出 require
+	mf, err = ParseModFile("module example.com/m\n\nrequire example.com/a v1.0.0\n\nreplace example.com/a => ../a\n\nunknown directive\n")
+	if err == nil {
+		t.Error("ParseModFile 对无法识别的指令没有返回错误")
+	}
+	if len(mf.Requires) != 1 || mf.Requires[0].Path != "example.com/a" {
+		t.Errorf("Requires = %+v，期望宽松解析出 example.com/a", mf.Requires)
+	}
+}
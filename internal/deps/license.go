@@ -0,0 +1,96 @@
+package deps
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// licenseFiles 是模块根目录下可能存放许可证的文件名，按优先级排列。
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "LICENCE.md", "COPYING", "COPYING.md", "License", "license"}
+
+// licenseKinds 按许可证正文中的特征文字识别常见的许可证，先匹配的优先。
+var licenseKinds = []struct {
+	name   string
+	phrase []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"LGPL", []string{"GNU LIBRARY GENERAL PUBLIC LICENSE"}},
+	{"GPL", []string{"GNU GENERAL PUBLIC LICENSE"}},
+	{"MPL-2.0", []string{"Mozilla Public License"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software"}},
+	{"Unlicense", []string{"This is free and unencumbered software"}},
+}
+
+// Copyleft 报告许可证是否带有传染性（GPL 系列与 MPL）：引入或升级到这类许可证的依赖通常需要法务确认。
+func Copyleft(license string) bool {
+	return strings.Contains(license, "GPL") || strings.HasPrefix(license, "MPL")
+}
+
+// DefaultModCache 返回本机的模块缓存目录：环境变量 GOMODCACHE，或 go env GOMODCACHE 的输出。
+// 都无法获取时返回空字符串。
+func DefaultModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	out, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// detectLicense 在模块缓存 cache 中识别 module@version 的许可证。
+// 模块不在缓存中或没有许可证文件时返回空字符串，无法识别时返回 "未识别"。
+func detectLicense(cache, module, version string) string {
+	if cache == "" {
+		return ""
+	}
+	dir := filepath.Join(cache, filepath.FromSlash(escapePath(module))+"@"+escapePath(version))
+	for _, name := range licenseFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		return classifyLicense(string(data))
+	}
+	return ""
+}
+
+// classifyLicense 根据许可证正文识别许可证类型。
+func classifyLicense(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, k := range licenseKinds {
+		matched := true
+		for _, p := range k.phrase {
+			if !strings.Contains(text, p) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return k.name
+		}
+	}
+	return "未识别"
+}
+
+// escapePath 按模块缓存的规则转义模块路径或版本：大写字母转换为 "!" 加对应的小写字母。
+func escapePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
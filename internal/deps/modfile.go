@@ -0,0 +1,103 @@
+package deps
+
+import (
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Require 是 go.mod 中的一条 require，Line 为其所在的行号（从 1 开始）。
+type Require struct {
+	Path     string
+	Version  string
+	Indirect bool
+	Line     int
+}
+
+// Replace 是 go.mod 中的一条 replace 指令：Old（OldVersion 为空时匹配全部版本）替换为 New@NewVersion。
+// New 为本地路径时 NewVersion 为空。
+type Replace struct {
+	Old        string
+	OldVersion string
+	New        string
+	NewVersion string
+	Line       int
+}
+
+// Local 报告 replace 的目标是否为本地路径。
+func (r Replace) Local() bool {
+	return modfile.IsDirectoryPath(r.New)
+}
+
+// String 返回形如 "a v1.0.0 => b v1.1.0" 的写法，与 go.mod 中的一致。
+func (r Replace) String() string {
+	old, repl := r.Old, r.New
+	if r.OldVersion != "" {
+		old += " " + r.OldVersion
+	}
+	if r.NewVersion != "" {
+		repl += " " + r.NewVersion
+	}
+	return old + " => " + repl
+}
+
+// ModFile 是从 go.mod 中解析出的依赖信息，只包含依赖检查需要的部分。
+type ModFile struct {
+	Module   string
+	Requires []Require
+	Replaces []Replace
+}
+
+// ParseModFile 用 golang.org/x/mod/modfile 解析 go.mod 的内容。data 为空（文件不存在）时返回空的 ModFile。
+//
+// 出错（例如语法错误或无法识别的指令）时返回该错误，同时尽量宽松地解析出 require：
+// modfile.ParseLax 会忽略 replace 指令（它只对主模块生效），因此只在严格解析失败时使用，此时 Replaces 为空。
+func ParseModFile(data string) (*ModFile, error) {
+	mf := &ModFile{}
+	if strings.TrimSpace(data) == "" {
+		return mf, nil
+	}
+
+	f, err := modfile.Parse("go.mod", []byte(data), nil)
+	if err != nil {
+		lax, laxErr := modfile.ParseLax("go.mod", []byte(data), nil)
+		if laxErr != nil {
+			return mf, err
+		}
+		f = lax
+	}
+	if f.Module != nil {
+		mf.Module = f.Module.Mod.Path
+	}
+	for _, r := range f.Require {
+		mf.Requires = append(mf.Requires, Require{
+			Path:     r.Mod.Path,
+			Version:  r.Mod.Version,
+			Indirect: r.Indirect,
+			Line:     r.Syntax.Start.Line,
+		})
+	}
+	for _, r := range f.Replace {
+		mf.Replaces = append(mf.Replaces, Replace{
+			Old:        r.Old.Path,
+			OldVersion: r.Old.Version,
+			New:        r.New.Path,
+			NewVersion: r.New.Version,
+			Line:       r.Syntax.Start.Line,
+		})
+	}
+	return mf, err
+}
+
+// parseSumFile 解析 go.sum，返回 "模块 版本"（版本可能带 "/go.mod" 后缀）到校验和的映射。
+func parseSumFile(data string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		sums[fields[0]+" "+fields[1]] = fields[2]
+	}
+	return sums
+}
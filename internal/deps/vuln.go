@@ -0,0 +1,183 @@
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Vuln 是离线漏洞库中影响某个依赖新版本的一条漏洞。
+//
+// - ID: 漏洞库中的编号，例如 "GO-2024-2687"
+// - Aliases: 其他编号，例如 CVE、GHSA
+// - Fixed: 修复了该漏洞的最低版本，漏洞尚未修复时为空
+type Vuln struct {
+	ID      string
+	Aliases []string
+	Summary string
+	Module  string
+	Version string
+	Fixed   string
+}
+
+// osvEntry 是漏洞库中 ID/<id>.json 的内容（OSV 格式），只包含需要的字段。
+type osvEntry struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Details  string   `json:"details"`
+	Affected []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+// moduleIndex 是漏洞库中 index/modules.json 的一项。
+type moduleIndex struct {
+	Path  string `json:"path"`
+	Vulns []struct {
+		ID string `json:"id"`
+	} `json:"vulns"`
+}
+
+// vulnDBDir 把漏洞库地址（目录或 file:// URL）转换为本地目录。
+func vulnDBDir(db string) (string, error) {
+	if !strings.HasPrefix(db, "file://") {
+		return db, nil
+	}
+	u, err := url.Parse(db)
+	if err != nil {
+		return "", fmt.Errorf("解析漏洞库地址 %s 失败: %w", db, err)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// checkVulns 在离线漏洞库 db 中查找影响 mods（模块 → 版本）的漏洞，结果按模块与编号排序。
+//
+// db 使用 govulncheck 的离线漏洞库格式（与 vuln.go.dev 的目录结构相同）：
+// index/modules.json 列出每个模块的漏洞编号，ID/<id>.json 为 OSV 格式的漏洞详情。
+func checkVulns(db string, mods map[string]string) ([]Vuln, error) {
+	dir, err := vulnDBDir(db)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "index", "modules.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取漏洞库索引失败: %w", err)
+	}
+	var index []moduleIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("解析漏洞库索引失败: %w", err)
+	}
+
+	var vulns []Vuln
+	for _, m := range index {
+		version, ok := mods[m.Path]
+		if !ok {
+			continue
+		}
+		for _, v := range m.Vulns {
+			entry, err := readOSV(dir, v.ID)
+			if err != nil {
+				return nil, err
+			}
+			if affected, fixed := entry.affects(m.Path, version); affected {
+				vulns = append(vulns, Vuln{
+					ID:      entry.ID,
+					Aliases: entry.Aliases,
+					Summary: entry.summary(),
+					Module:  m.Path,
+					Version: version,
+					Fixed:   fixed,
+				})
+			}
+		}
+	}
+
+	sort.Slice(vulns, func(i, j int) bool {
+		if vulns[i].Module != vulns[j].Module {
+			return vulns[i].Module < vulns[j].Module
+		}
+		return vulns[i].ID < vulns[j].ID
+	})
+	return vulns, nil
+}
+
+// readOSV 读取漏洞库中编号为 id 的漏洞详情。
+func readOSV(dir, id string) (*osvEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "ID", id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取漏洞 %s 失败: %w", id, err)
+	}
+	var entry osvEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("解析漏洞 %s 失败: %w", id, err)
+	}
+	return &entry, nil
+}
+
+// affects 报告漏洞是否影响 module 的 version，并返回修复了该漏洞的版本（带 "v" 前缀）。
+// 每个 SEMVER 区间由依次出现的 introduced 与 fixed 事件组成，version 落在 [introduced, fixed) 中即受影响。
+func (e *osvEntry) affects(module, version string) (bool, string) {
+	for _, a := range e.Affected {
+		if a.Package.Name != module {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			intro, open := "", false
+			for _, ev := range r.Events {
+				if ev.Introduced != "" {
+					intro, open = ev.Introduced, true
+				}
+				if ev.Fixed != "" && open {
+					if introduced(version, intro) && semver.Compare(version, osvVersion(ev.Fixed)) < 0 {
+						return true, "v" + ev.Fixed
+					}
+					open = false
+				}
+			}
+			if open && introduced(version, intro) {
+				return true, ""
+			}
+		}
+	}
+	return false, ""
+}
+
+// introduced 报告 version 是否不早于 OSV 的 introduced 事件 intro。intro 为 "0" 时包含全部版本，
+// 包括 v0.0.0-20200101000000-abcdefabcdef 这样排在 v0.0.0 之前的伪版本。
+func introduced(version, intro string) bool {
+	return intro == "0" || semver.Compare(version, osvVersion(intro)) >= 0
+}
+
+// osvVersion 给 OSV 中不带 "v" 前缀的版本（如 "1.2.3"，"0" 表示最早的版本）加上前缀，以便用 semver 包比较。
+func osvVersion(v string) string {
+	return "v" + v
+}
+
+// summary 返回漏洞的一句话概括，没有 summary 时取 details 的第一行。
+func (e *osvEntry) summary() string {
+	if e.Summary != "" {
+		return e.Summary
+	}
+	details, _, _ := strings.Cut(strings.TrimSpace(e.Details), "\n")
+	return details
+}
@@ -0,0 +1,93 @@
+package deps
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testVulnDB 是 testdata 中的离线漏洞库：
+//
+// - GO-0000-0001: example.com/open 从 1.2.0 起受影响，尚未修复
+// - GO-0000-0002: example.com/multi 的 [1.0.0, 1.1.0) 与 [1.3.0, 1.3.5)，另有一个应被忽略的 ECOSYSTEM 区间
+// - GO-0000-0003: example.com/zero 在 0.5.0 之前的全部版本（introduced 为 "0"）
+// - GO-0000-0004: 列在 example.com/zero 下，但 affected 中只有 example.com/other
+const testVulnDB = "testdata/vulndb"
+
+func TestAffects(t *testing.T) {
+	tests := []struct {
+		id      string
+		module  string
+		version string
+		want    bool
+		fixed   string
+	}{
+		{"GO-0000-0001", "example.com/open", "v1.1.9", false, ""},
+		{"GO-0000-0001", "example.com/open", "v1.2.0", true, ""},
+		{"GO-0000-0001", "example.com/open", "v9.0.0", true, ""},
+		{"GO-0000-0001", "example.com/other", "v1.2.0", false, ""},
+
+		{"GO-0000-0002", "example.com/multi", "v0.9.0", false, ""},
+		{"GO-0000-0002", "example.com/multi", "v1.0.0", true, "v1.1.0"},
+		{"GO-0000-0002", "example.com/multi", "v1.0.9", true, "v1.1.0"},
+		{"GO-0000-0002", "example.com/multi", "v1.1.0", false, ""},
+		{"GO-0000-0002", "example.com/multi", "v1.2.0", false, ""}, // 两个区间之间
+		{"GO-0000-0002", "example.com/multi", "v1.3.0", true, "v1.3.5"},
+		{"GO-0000-0002", "example.com/multi", "v1.3.5", false, ""},
+		{"GO-0000-0002", "example.com/multi", "v1.4.0", false, ""},
+
+		{"GO-0000-0003", "example.com/zero", "v0.0.0-20200101000000-abcdefabcdef", true, "v0.5.0"},
+		{"GO-0000-0003", "example.com/zero", "v0.0.1", true, "v0.5.0"},
+		{"GO-0000-0003", "example.com/zero", "v0.4.9", true, "v0.5.0"},
+		{"GO-0000-0003", "example.com/zero", "v0.5.0", false, ""},
+		{"GO-0000-0003", "example.com/zero", "v1.0.0", false, ""},
+
+		{"GO-0000-0004", "example.com/zero", "v0.1.0", false, ""},
+	}
+	for _, tt := range tests {
+		entry, err := readOSV(testVulnDB, tt.id)
+		if err != nil {
+			t.Fatalf("readOSV(%s) 返回错误：%v", tt.id, err)
+		}
+		got, fixed := entry.affects(tt.module, tt.version)
+		if got != tt.want || fixed != tt.fixed {
+			t.Errorf("%s affects(%s, %s) = %v, %q，期望 %v, %q", tt.id, tt.module, tt.version, got, fixed, tt.want, tt.fixed)
+		}
+	}
+}
+
+func TestCheckVulns(t *testing.T) {
+	abs, err := filepath.Abs(testVulnDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, db := range []string{testVulnDB, "file://" + filepath.ToSlash(abs)} {
+		vulns, err := checkVulns(db, map[string]string{
+			"example.com/open":  "v1.2.3",
+			"example.com/multi": "v1.2.0",
+			"example.com/zero":  "v0.3.0",
+		})
+		if err != nil {
+			t.Fatalf("checkVulns(%s) 返回错误：%v", db, err)
+		}
+
+		want := []Vuln{
+			{ID: "GO-0000-0001", Aliases: []string{"CVE-0000-0001"}, Summary: "Open-ended range in example.com/open", Module: "example.com/open", Version: "v1.2.3"},
+			{ID: "GO-0000-0003", Summary: "Every version before 0.5.0 of example.com/zero", Module: "example.com/zero", Version: "v0.3.0", Fixed: "v0.5.0"},
+		}
+		if !reflect.DeepEqual(vulns, want) {
+			t.Errorf("checkVulns(%s) = %+v，期望 %+v", db, vulns, want)
+		}
+	}
+}
+
+func TestCheckVulnsSummaryFromDetails(t *testing.T) {
+	vulns, err := checkVulns(testVulnDB, map[string]string{"example.com/multi": "v1.3.1"})
+	if err != nil {
+		t.Fatalf("checkVulns 返回错误：%v", err)
+	}
+	if len(vulns) != 1 || vulns[0].Summary != "Several ranges in example.com/multi." || vulns[0].Fixed != "v1.3.5" {
+		t.Errorf("checkVulns = %+v，期望一条取 details 第一行作为概括、修复版本为 v1.3.5 的漏洞", vulns)
+	}
+}
@@ -3,7 +3,9 @@ package gitops
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -95,4 +97,283 @@ func GetChangedFiles() ([]string, error) {
 	return files, nil
 }
 
+// GetStagedFiles 返回暂存区中有变更的全部文件（不限于 .go 文件），等价于：
+//
+//	git diff --cached --name-only
+func GetStagedFiles() ([]string, error) {
+	out, err := runGit("", "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// GetFileStagedDiff 获取单个文件在暂存区中的 diff（仅该文件），等价于：
+//
+//	git diff --cached --unified=0 -- <file>
+func GetFileStagedDiff(file string) (string, error) {
+	return getFileStagedDiff(file, 0)
+}
+
+// GetFileStagedDiffWithContext 与 GetFileStagedDiff 相同，但保留 context 行上下文，
+// 适合直接展示给人阅读，等价于：
+//
+//	git diff --cached --unified=<context> -- <file>
+func GetFileStagedDiffWithContext(file string, context int) (string, error) {
+	return getFileStagedDiff(file, context)
+}
+
+func getFileStagedDiff(file string, context int) (string, error) {
+	cmd := exec.Command("git", "diff", "--cached", fmt.Sprintf("--unified=%d", context), "--", file)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
 
+	if err != nil {
+		if strings.Contains(output, "not a git repository") {
+			return "", fmt.Errorf("当前目录不是 git 仓库：%s", output)
+		}
+		if output != "" {
+			return "", fmt.Errorf("执行 git diff 失败：%s", output)
+		}
+		return "", fmt.Errorf("执行 git diff 失败：%w", err)
+	}
+
+	if output == "" {
+		return "", fmt.Errorf("文件 %s 在暂存区没有 diff 输出", file)
+	}
+
+	return output, nil
+}
+
+// GetRepoRoot 返回当前 Git 仓库工作区的根目录（绝对路径），等价于：
+//
+//	git rev-parse --show-toplevel
+//
+// git diff 输出的文件路径都是相对于仓库根目录的，需要在根目录下执行的外部工具
+// （如 go vet）应当以此作为工作目录。
+func GetRepoRoot() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("git 未安装或不在 PATH 中: %w", err)
+		}
+		if strings.Contains(output, "not a git repository") {
+			return "", fmt.Errorf("当前目录不是 git 仓库: %s", output)
+		}
+		if output != "" {
+			return "", fmt.Errorf("执行 git rev-parse 失败: %s", output)
+		}
+		return "", fmt.Errorf("执行 git rev-parse 失败: %w", err)
+	}
+
+	return output, nil
+}
+
+// ParseChangedLines 从 unified diff 中解析出新文件一侧被新增或修改的行号集合。
+//
+// 只依赖 hunk 头（@@ -a,b +c,d @@），因此对 --unified=0 的输出最为精确；
+// 对带上下文的 diff，上下文行也会被计入。纯删除的 hunk（d == 0）不产生任何行号。
+func ParseChangedLines(diff string) map[int]bool {
+	lines := make(map[int]bool)
+
+	for _, line := range strings.Split(diff, "\n") {
+		if !strings.HasPrefix(line, "@@") {
+			continue
+		}
+
+		start, count, ok := parseHunkNewRange(line)
+		if !ok {
+			continue
+		}
+		for i := 0; i < count; i++ {
+			lines[start+i] = true
+		}
+	}
+
+	return lines
+}
+
+// parseHunkNewRange 解析 hunk 头中 "+c,d" 部分，返回起始行号与行数。
+// 省略 ",d" 时行数为 1。
+func parseHunkNewRange(header string) (start, count int, ok bool) {
+	fields := strings.Fields(header)
+	for _, f := range fields {
+		if !strings.HasPrefix(f, "+") {
+			continue
+		}
+
+		f = strings.TrimPrefix(f, "+")
+		count = 1
+		if i := strings.IndexByte(f, ','); i >= 0 {
+			if _, err := fmt.Sscanf(f[i+1:], "%d", &count); err != nil {
+				return 0, 0, false
+			}
+			f = f[:i]
+		}
+		if _, err := fmt.Sscanf(f, "%d", &start); err != nil {
+			return 0, 0, false
+		}
+		return start, count, true
+	}
+
+	return 0, 0, false
+}
+
+// GetStagedFileContent 返回文件在暂存区中的完整内容，等价于：
+//
+//	git show :<file>
+func GetStagedFileContent(file string) (string, error) {
+	cmd := exec.Command("git", "show", ":"+file)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("读取暂存区文件 %s 失败: %s", file, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("读取暂存区文件 %s 失败: %w", file, err)
+	}
+
+	return string(out), nil
+}
+
+// CheckPatch 校验 patch 能否干净地应用，等价于：
+//
+//	git apply --check --recount [--cached] -
+//
+// cached 为 true 时针对暂存区校验，否则针对工作区。--recount 让 git 忽略 hunk 头中的行数，
+// 以容忍 LLM 生成补丁时常见的计数错误。
+func CheckPatch(patch string, cached bool) error {
+	args := []string{"apply", "--check", "--recount"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	return runGitWithInput("", patch, append(args, "-")...)
+}
+
+// ApplyPatch 把 patch 应用到暂存区（cached 为 true）或工作区，等价于：
+//
+//	git apply --recount [--cached] -
+func ApplyPatch(patch string, cached bool) error {
+	args := []string{"apply", "--recount"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	return runGitWithInput("", patch, append(args, "-")...)
+}
+
+// GetHead 返回 HEAD 指向的提交与当前分支名。仓库还没有提交时 commit 为空，
+// 处于分离 HEAD 状态时 branch 为 "HEAD"。
+func GetHead() (commit, branch string, err error) {
+	// 没有任何提交时 rev-parse --verify 以非零状态退出，这不算错误
+	commit, _ = runGit("", "rev-parse", "--verify", "--quiet", "HEAD")
+
+	branch, err = runGit("", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil && commit == "" {
+		branch, err = runGit("", "symbolic-ref", "--short", "HEAD")
+	}
+	return commit, branch, err
+}
+
+// StageFile 把 file（相对仓库根目录的路径）在工作区中的当前内容加入暂存区。
+func StageFile(file string) error {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	_, err = runGit(root, "add", "--", file)
+	return err
+}
+
+// runGitWithInput 在 dir 下以 input 作为标准输入执行 git 命令，失败时把 git 的输出作为错误信息返回。
+//
+// dir 为空时使用仓库根目录：补丁中的路径都相对于仓库根目录，
+// 而 git apply 在子目录中执行时会忽略目录外的路径。
+func runGitWithInput(dir, input string, args ...string) error {
+	if dir == "" {
+		root, err := GetRepoRoot()
+		if err != nil {
+			return err
+		}
+		dir = root
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("git 未安装或不在 PATH 中: %w", err)
+		}
+		if output != "" {
+			return fmt.Errorf("执行 git %s 失败: %s", args[0], output)
+		}
+		return fmt.Errorf("执行 git %s 失败: %w", args[0], err)
+	}
+
+	return nil
+}
+
+// runGit 在 dir（为空时为当前目录）下执行 git 命令，返回去掉首尾空白的标准输出。
+func runGit(dir string, args ...string) (string, error) {
+	out, err := runGitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+// runGitRaw 与 runGit 相同，但原样返回标准输出。
+// diff 等输出中行尾空白有意义（如上下文行只有一个空格），不能去掉。
+func runGitRaw(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("执行 git %s 失败: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("执行 git %s 失败: %w", args[0], err)
+	}
+
+	return string(out), nil
+}
+
+// GetStateDir 返回 review-go 在当前仓库中保存本地状态的目录（<git-common-dir>/review-go），
+// 不存在时自动创建。
+//
+// 使用 --git-common-dir 而不是 --git-dir，使得在多个 worktree 中运行时共享同一份状态。
+func GetStateDir() (string, error) {
+	dir, err := runGit("", "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(dir) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("解析 git 目录失败: %w", err)
+		}
+		dir = abs
+	}
+
+	dir = filepath.Join(dir, "review-go")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建状态目录 %s 失败: %w", dir, err)
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,170 @@
+package gitops
+
+import (
+	"strings"
+)
+
+// Hunk 是 unified diff 中的一个 hunk。
+//
+// - Header: hunk 头，形如 "@@ -a,b +c,d @@ func name"
+// - Lines: hunk 正文，每行保留 '+'、'-'、' ' 或 '\' 前缀
+type Hunk struct {
+	Header string
+	Lines  []string
+}
+
+// Stats 返回 hunk 中新增与删除的行数。
+func (h Hunk) Stats() (added, removed int) {
+	for _, l := range h.Lines {
+		switch {
+		case strings.HasPrefix(l, "+"):
+			added++
+		case strings.HasPrefix(l, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// FileHunks 是单个文件的 diff 拆分成的 hunk 列表。
+//
+// - Header: 第一个 hunk 之前的文件头（diff --git、index、---、+++ 等），生成单个 hunk 的补丁时需要
+// - Staged: 是暂存区相对 HEAD 的 diff（true），还是工作区相对暂存区的 diff（false）
+type FileHunks struct {
+	File   string
+	Header string
+	Staged bool
+	Hunks  []Hunk
+}
+
+// GetFileHunks 读取 file（相对仓库根目录的路径）的 diff 并拆分为 hunk，等价于：
+//
+//	git diff [--cached] -- <file>
+//
+// staged 为 true 时读取暂存区的 diff，否则读取工作区中尚未暂存的修改。没有修改时 Hunks 为空。
+func GetFileHunks(file string, staged bool) (*FileHunks, error) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	// 固定输出格式，避免用户的 color / 外部 diff / 前缀配置影响解析与 git apply
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	out, err := runGitRaw(root, append(args, "--", file)...)
+	if err != nil {
+		return nil, err
+	}
+
+	fh := ParseHunks(out)
+	fh.File = file
+	fh.Staged = staged
+	return fh, nil
+}
+
+// ParseHunks 把单个文件的 unified diff 拆分为文件头与 hunk 列表。
+func ParseHunks(diff string) *FileHunks {
+	fh := &FileHunks{}
+
+	var header []string
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for _, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "@@"):
+			fh.Hunks = append(fh.Hunks, Hunk{Header: l})
+		case len(fh.Hunks) == 0:
+			header = append(header, l)
+		default:
+			h := &fh.Hunks[len(fh.Hunks)-1]
+			h.Lines = append(h.Lines, l)
+		}
+	}
+
+	fh.Header = strings.Join(header, "\n")
+	return fh
+}
+
+// Patch 返回只包含第 i 个 hunk 的补丁，可直接交给 git apply。
+func (fh *FileHunks) Patch(i int) string {
+	h := fh.Hunks[i]
+
+	var b strings.Builder
+	b.WriteString(fh.Header)
+	b.WriteString("\n")
+	b.WriteString(h.Header)
+	b.WriteString("\n")
+	for _, l := range h.Lines {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Body 返回 hunk 的正文（不含 hunk 头），用于在行号变化后识别同一个 hunk。
+func (h Hunk) Body() string {
+	return strings.Join(h.Lines, "\n")
+}
+
+// UnstagedHunkBodies 返回 file 在工作区中尚未暂存的各个 hunk 的正文（见 Hunk.Body），读取失败时返回 nil。
+func UnstagedHunkBodies(file string) map[string]bool {
+	fh, err := GetFileHunks(file, false)
+	if err != nil {
+		return nil
+	}
+	bodies := make(map[string]bool, len(fh.Hunks))
+	for _, h := range fh.Hunks {
+		bodies[h.Body()] = true
+	}
+	return bodies
+}
+
+// StageHunksExcept 把 file 工作区 diff 中正文不在 keep 中的 hunk 加入暂存区，返回暂存的 hunk 数。
+// keep 通常是编辑之前就已存在的未暂存 hunk（见 UnstagedHunkBodies），这样只暂存编辑器中新做的修改；
+// keep 为空时等价于 StageFile。
+func StageHunksExcept(file string, keep map[string]bool) (int, error) {
+	if len(keep) == 0 {
+		return 0, StageFile(file)
+	}
+
+	fh, err := GetFileHunks(file, false)
+	if err != nil {
+		return 0, err
+	}
+	staged := 0
+	// 从后往前暂存，前面 hunk 的行号不受影响
+	for i := len(fh.Hunks) - 1; i >= 0; i-- {
+		if keep[fh.Hunks[i].Body()] {
+			continue
+		}
+		if err := StageHunk(fh, i); err != nil {
+			return staged, err
+		}
+		staged++
+	}
+	return staged, nil
+}
+
+// StageHunk 把工作区 diff 中的第 i 个 hunk 加入暂存区，等价于在 git add -p 中对该 hunk 选择 y：
+//
+//	git apply --cached
+func StageHunk(fh *FileHunks, i int) error {
+	return runGitWithInput("", fh.Patch(i), "apply", "--cached", "-")
+}
+
+// UnstageHunk 把暂存区 diff 中的第 i 个 hunk 从暂存区撤回（工作区不变），等价于 git reset -p：
+//
+//	git apply --cached --reverse
+func UnstageHunk(fh *FileHunks, i int) error {
+	return runGitWithInput("", fh.Patch(i), "apply", "--cached", "--reverse", "-")
+}
+
+// DiscardHunk 丢弃工作区 diff 中第 i 个 hunk 的修改，等价于 git checkout -p：
+//
+//	git apply --reverse
+//
+// 该操作不可撤销，调用方应当先向用户确认。
+func DiscardHunk(fh *FileHunks, i int) error {
+	return runGitWithInput("", fh.Patch(i), "apply", "--reverse", "-")
+}
@@ -0,0 +1,245 @@
+package gitops
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// EmptyTree 是 git 中空树对象的哈希。与它比较可以得到仓库第一个提交引入的全部变更。
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// GetRangeChangedFiles 返回 base 与 head 两个提交之间有变更的 .go 文件列表，等价于：
+//
+//	git diff --name-only <base> <head> -- *.go
+func GetRangeChangedFiles(base, head string) ([]string, error) {
+	out, err := runGit("", "diff", "--name-only", base, head, "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// GetRangeFiles 返回 base 与 head 两个提交之间有变更的全部文件（不限于 .go 文件），等价于：
+//
+//	git diff --name-only <base> <head>
+func GetRangeFiles(base, head string) ([]string, error) {
+	out, err := runGit("", "diff", "--name-only", base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// GetFileRangeDiff 获取单个文件在 base 与 head 两个提交之间的 diff，等价于：
+//
+//	git diff --unified=0 <base> <head> -- <file>
+func GetFileRangeDiff(base, head, file string) (string, error) {
+	return GetFileRangeDiffWithContext(base, head, file, 0)
+}
+
+// GetFileRangeDiffWithContext 与 GetFileRangeDiff 相同，但保留 context 行上下文。
+func GetFileRangeDiffWithContext(base, head, file string, context int) (string, error) {
+	out, err := runGitRaw("", "diff", fmt.Sprintf("--unified=%d", context), base, head, "--", file)
+	if err != nil {
+		return "", err
+	}
+
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return "", fmt.Errorf("文件 %s 在 %s..%s 之间没有 diff 输出", file, base, head)
+	}
+	return out, nil
+}
+
+// GetFileContentAt 返回文件在提交 rev 中的完整内容，等价于：
+//
+//	git show <rev>:<file>
+func GetFileContentAt(rev, file string) (string, error) {
+	out, err := runGitRaw("", "show", rev+":"+file)
+	if err != nil {
+		return "", fmt.Errorf("读取 %s 中的文件 %s 失败: %w", rev, file, err)
+	}
+	return out, nil
+}
+
+// GetDirFilesAt 返回提交 rev 中目录 dir（相对仓库根目录，"." 为根目录）直接包含的文件，不含子目录中的文件，
+// 路径相对仓库根目录。等价于：
+//
+//	git ls-tree -r --full-tree --name-only <rev> -- <dir>/
+func GetDirFilesAt(rev, dir string) ([]string, error) {
+	args := []string{"ls-tree", "-r", "--full-tree", "--name-only", rev}
+	if dir != "." {
+		args = append(args, "--", dir+"/")
+	}
+	out, err := runGit("", args...)
+	if err != nil {
+		return nil, fmt.Errorf("列出 %s 中的目录 %s 失败: %w", rev, dir, err)
+	}
+	return dirChildren(out, dir), nil
+}
+
+// GetStagedDirFiles 返回暂存区中目录 dir（相对仓库根目录，"." 为根目录）直接包含的文件，不含子目录中的文件，
+// 路径相对仓库根目录。等价于：
+//
+//	git ls-files --cached --full-name -- :(top)<dir>/
+func GetStagedDirFiles(dir string) ([]string, error) {
+	spec := ":(top)"
+	if dir != "." {
+		spec += dir + "/"
+	}
+	out, err := runGit("", "ls-files", "--cached", "--full-name", "--", spec)
+	if err != nil {
+		return nil, fmt.Errorf("列出暂存区中的目录 %s 失败: %w", dir, err)
+	}
+	return dirChildren(out, dir), nil
+}
+
+// dirChildren 从 git 输出的文件列表（每行一个路径）中挑出直接位于 dir 下的文件。
+func dirChildren(out, dir string) []string {
+	var files []string
+	for _, f := range strings.Split(out, "\n") {
+		if f = strings.TrimSpace(f); f != "" && path.Dir(f) == dir {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// GetRangeLog 返回 base 与 head 之间的提交（不含 base），按时间从早到晚排列，
+// 每个提交为 "- <短哈希> <标题>"，正文缩进两格跟在后面。最多返回 limit 个提交（limit <= 0 时不限制）。
+func GetRangeLog(base, head string, limit int) (string, error) {
+	args := []string{"log", "--reverse", "--no-merges", "--format=- %h %s%n%w(0,2,2)%b"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	out, err := runGit("", append(args, base+".."+head)...)
+	if err != nil {
+		return "", err
+	}
+
+	// 去掉没有正文的提交留下的空行
+	var lines []string
+	for _, l := range strings.Split(out, "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, strings.TrimRight(l, " "))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// CommitExists 报告 rev 是否是本地存在的提交。
+func CommitExists(rev string) bool {
+	_, err := runGit("", "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	return err == nil
+}
+
+// GetMergeBase 返回 a 与 b 的最近公共祖先，等价于：
+//
+//	git merge-base <a> <b>
+func GetMergeBase(a, b string) (string, error) {
+	return runGit("", "merge-base", a, b)
+}
+
+// GetUnpushedBase 返回 head 中尚未出现在任何远程跟踪分支上的提交的起点：所有未推送提交的共同祖先，
+// 即这些提交的边界提交（已推送的父提交）的最近公共祖先，因此 <起点>..<head> 一定包含每个未推送的提交。
+// 有未推送的提交没有父提交（例如仓库的第一个提交），或边界提交之间没有公共祖先时返回 EmptyTree。
+// head 上的提交都已推送过时返回空字符串。
+//
+// 用于推送新分支时确定需要审查的范围，边界提交来自下面命令输出中以 "-" 开头的行：
+//
+//	git rev-list --boundary <head> --not --remotes
+//
+// 只取最早一个未推送提交的父提交是不够的：未推送的范围中有合并提交时，它不一定是其他未推送提交的祖先。
+func GetUnpushedBase(head string) (string, error) {
+	out, err := runGit("", "rev-list", "--boundary", head, "--not", "--remotes")
+	if err != nil {
+		return "", err
+	}
+
+	var unpushed, boundary []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "-"):
+			boundary = append(boundary, line[1:])
+		default:
+			unpushed = append(unpushed, line)
+		}
+	}
+	if len(unpushed) == 0 {
+		return "", nil
+	}
+
+	roots, err := runGit("", "rev-list", "--max-parents=0", head, "--not", "--remotes")
+	if err != nil {
+		return "", err
+	}
+	if roots != "" || len(boundary) == 0 {
+		return EmptyTree, nil
+	}
+	if len(boundary) == 1 {
+		return boundary[0], nil
+	}
+	// 没有公共祖先时 git merge-base 以非零状态退出
+	if base, err := runGit("", append([]string{"merge-base", "--octopus"}, boundary...)...); err == nil && base != "" {
+		return base, nil
+	}
+	return EmptyTree, nil
+}
+
+// GetHooksDir 返回当前仓库的 hooks 目录（绝对路径）。设置了 core.hooksPath 时返回该目录。
+func GetHooksDir() (string, error) {
+	dir, err := runGit("", "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(dir) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("解析 hooks 目录失败: %w", err)
+		}
+		dir = abs
+	}
+	return dir, nil
+}
+
+// GetChurn 统计最近 limit 个提交（不含合并提交）中每个文件被修改的次数，等价于统计下面命令输出中各文件出现的次数：
+//
+//	git log -n <limit> --no-merges --format= --name-only
+//
+// 仓库还没有任何提交时返回空结果。
+func GetChurn(limit int) (map[string]int, error) {
+	churn := make(map[string]int)
+	if !CommitExists("HEAD") {
+		return churn, nil
+	}
+
+	out, err := runGit("", "log", fmt.Sprintf("-n%d", limit), "--no-merges", "--format=", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			churn[line]++
+		}
+	}
+	return churn, nil
+}
@@ -0,0 +1,124 @@
+package gitops
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// testRepo 在临时目录中创建一个 git 仓库并切换到该目录，测试结束后切换回来。
+// 返回的 git 函数在仓库中执行 git 命令并返回去掉首尾空白的输出；date 不为空时用作提交的作者与提交时间。
+func testRepo(t *testing.T) (git func(date string, args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git 不在 PATH 中")
+	}
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	git = func(date string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1")
+		if date != "" {
+			cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		}
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s 失败：%v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("", "init", "-q", "-b", "main")
+	return git
+}
+
+// commit 在当前分支上修改 file 并提交，返回新提交的哈希。
+func commit(t *testing.T, git func(string, ...string) string, date, file string) string {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(date+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(date, "add", file)
+	git(date, "commit", "-q", "-m", file)
+	return git("", "rev-parse", "HEAD")
+}
+
+func TestGetUnpushedBase(t *testing.T) {
+	git := testRepo(t)
+
+	// 还没有任何远程跟踪分支：全部提交都未推送，起点为空树
+	root := commit(t, git, "2024-01-01T00:00:00Z", "root.txt")
+	if base, err := GetUnpushedBase(root); err != nil || base != EmptyTree {
+		t.Fatalf("GetUnpushedBase = %q, %v，期望空树", base, err)
+	}
+
+	pushed := commit(t, git, "2024-01-02T00:00:00Z", "pushed.txt")
+	git("", "update-ref", "refs/remotes/origin/main", pushed)
+	if base, err := GetUnpushedBase(pushed); err != nil || base != "" {
+		t.Fatalf("全部已推送时 GetUnpushedBase = %q, %v，期望空字符串", base, err)
+	}
+
+	linear := commit(t, git, "2024-01-03T00:00:00Z", "linear.txt")
+	if base, err := GetUnpushedBase(linear); err != nil || base != pushed {
+		t.Fatalf("线性历史中 GetUnpushedBase = %q, %v，期望 %s", base, err, pushed)
+	}
+	git("", "reset", "-q", "--hard", pushed)
+
+	// 未推送的范围中有合并提交：side 从更早的 root 分出，mainline 的提交时间最早。
+	// 只取最早的未推送提交的父提交会得到 pushed，它不是 side 的祖先
+	git("", "checkout", "-q", "-b", "side", root)
+	side := commit(t, git, "2024-01-05T00:00:00Z", "side.txt")
+	git("", "checkout", "-q", "main")
+	mainline := commit(t, git, "2023-12-01T00:00:00Z", "mainline.txt")
+	git("2024-01-06T00:00:00Z", "merge", "-q", "--no-edit", "side")
+	head := git("", "rev-parse", "HEAD")
+
+	base, err := GetUnpushedBase(head)
+	if err != nil {
+		t.Fatalf("GetUnpushedBase 返回错误：%v", err)
+	}
+	if base != root {
+		t.Errorf("GetUnpushedBase = %s，期望 %s（root）", base, root)
+	}
+	for _, c := range []string{side, mainline} {
+		cmd := exec.Command("git", "merge-base", "--is-ancestor", base, c)
+		if err := cmd.Run(); err != nil {
+			t.Errorf("起点 %s 不是未推送提交 %s 的祖先", base, c)
+		}
+	}
+	listed := git("", "rev-list", base+".."+head)
+	for _, c := range []string{side, mainline, head} {
+		if !strings.Contains(listed, c) {
+			t.Errorf("%s..%s 中缺少未推送的提交 %s", base, head, c)
+		}
+	}
+}
+
+func TestGetUnpushedBaseUnrelatedHistory(t *testing.T) {
+	git := testRepo(t)
+
+	pushed := commit(t, git, "2024-01-01T00:00:00Z", "pushed.txt")
+	git("", "update-ref", "refs/remotes/origin/main", pushed)
+
+	// 合并一个无关历史的分支：其中的第一个提交没有父提交，只有空树才是共同起点
+	git("", "checkout", "-q", "--orphan", "other")
+	git("", "rm", "-q", "-rf", ".")
+	commit(t, git, "2024-01-02T00:00:00Z", "other.txt")
+	git("", "checkout", "-q", "main")
+	git("2024-01-03T00:00:00Z", "merge", "-q", "--no-edit", "--allow-unrelated-histories", "other")
+
+	if base, err := GetUnpushedBase(git("", "rev-parse", "HEAD")); err != nil || base != EmptyTree {
+		t.Errorf("GetUnpushedBase = %q, %v，期望空树", base, err)
+	}
+}
@@ -0,0 +1,71 @@
+package gitops
+
+import (
+	"fmt"
+	"os"
+)
+
+// ScratchWorktree 是一个检出了暂存区快照的临时 git worktree，
+// 用于在不影响用户工作区与暂存区的前提下试验补丁。
+//
+// 使用完毕后必须调用 Remove 清理。
+type ScratchWorktree struct {
+	// Dir 是临时 worktree 的根目录。
+	Dir string
+	// Tree 是暂存区快照对应的 tree 对象。
+	Tree string
+}
+
+// NewScratchWorktree 把当前暂存区写成 tree 对象，并在临时目录中创建一个检出该 tree 的 worktree。
+// 等价于：
+//
+//	git write-tree
+//	git worktree add --detach --no-checkout <tmp>
+//	git -C <tmp> read-tree -u --reset <tree>
+//
+// 仓库还没有任何提交时 git worktree add 会失败，此时返回错误。
+func NewScratchWorktree() (*ScratchWorktree, error) {
+	tree, err := runGit("", "write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "review-go-worktree-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+
+	if _, err := runGit("", "worktree", "add", "--detach", "--no-checkout", dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	wt := &ScratchWorktree{Dir: dir, Tree: tree}
+	if err := wt.Reset(); err != nil {
+		wt.Remove()
+		return nil, err
+	}
+
+	return wt, nil
+}
+
+// Apply 把 patch 同时应用到 worktree 的索引与文件，等价于：
+//
+//	git -C <dir> apply --index --recount -
+//
+// 同时更新索引是为了让 Reset 能够识别出被修改、新增的文件并将其还原。
+func (w *ScratchWorktree) Apply(patch string) error {
+	return runGitWithInput(w.Dir, patch, "apply", "--index", "--recount", "-")
+}
+
+// Reset 把 worktree 的索引与文件恢复为暂存区快照，丢弃之前应用的补丁。
+func (w *ScratchWorktree) Reset() error {
+	_, err := runGit(w.Dir, "read-tree", "-u", "--reset", w.Tree)
+	return err
+}
+
+// Remove 删除临时 worktree 及其目录。清理失败不影响调用方，只做尽力而为。
+func (w *ScratchWorktree) Remove() {
+	_, _ = runGit("", "worktree", "remove", "--force", w.Dir)
+	_ = os.RemoveAll(w.Dir)
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// FindingsDiff 是同一文件两次审查的问题对比结果。
+type FindingsDiff struct {
+	Both    []review.Finding
+	OnlyOld []review.Finding
+	OnlyNew []review.Finding
+}
+
+// CompareFindings 按问题指纹对比两次审查的问题；指纹缺失（旧版本记录）时退回按来源与标题匹配。
+func CompareFindings(old, new []review.Finding) FindingsDiff {
+	key := func(f review.Finding) string {
+		if f.Fingerprint != "" {
+			return f.Fingerprint
+		}
+		return f.Source + "\x00" + f.Title
+	}
+
+	oldKeys := make(map[string]bool, len(old))
+	for _, f := range old {
+		oldKeys[key(f)] = true
+	}
+	newKeys := make(map[string]bool, len(new))
+	for _, f := range new {
+		newKeys[key(f)] = true
+	}
+
+	var d FindingsDiff
+	for _, f := range new {
+		if oldKeys[key(f)] {
+			d.Both = append(d.Both, f)
+		} else {
+			d.OnlyNew = append(d.OnlyNew, f)
+		}
+	}
+	for _, f := range old {
+		if !newKeys[key(f)] {
+			d.OnlyOld = append(d.OnlyOld, f)
+		}
+	}
+	return d
+}
+
+// DiffLines 返回把 a 变为 b 的逐行差异，每行以 "  "（相同）、"- "（仅 a）或 "+ "（仅 b）开头。
+//
+// 基于最长公共子序列，复杂度为 O(len(a)·len(b))；审查报告通常只有几十到几百行，足够使用。
+func DiffLines(a, b string) []string {
+	x := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	y := strings.Split(strings.TrimRight(b, "\n"), "\n")
+
+	// lcs[i][j] 为 x[i:] 与 y[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, "  "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		out = append(out, "+ "+y[j])
+	}
+	return out
+}
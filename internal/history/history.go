@@ -0,0 +1,292 @@
+// Package history 把每次审查运行保存在本地的 bbolt 数据库中（<git-common-dir>/review-go/history.db），
+// 供 review-go history 命令回看与比较。
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// dbFile 是历史数据库在状态目录中的文件名。
+const dbFile = "history.db"
+
+// runsBucket 保存所有运行记录，key 为 8 字节大端序的运行编号，value 为 Run 的 JSON。
+var runsBucket = []byte("runs")
+
+// openTimeout 是等待数据库文件锁的时间。bbolt 同一时刻只允许一个进程写入，
+// 同时运行多个 review-go 时后来者最多等待这么久，超时则放弃本次写入。
+const openTimeout = 2 * time.Second
+
+// ErrNotFound 表示指定编号的运行记录不存在。
+var ErrNotFound = errors.New("审查记录不存在")
+
+// Run 是一次审查运行的记录。
+//
+// - ID: 自增的运行编号，从 1 开始
+// - Head / Branch: 运行时 HEAD 指向的提交与分支，便于回看针对某个提交的审查
+// - Files: 按文件保存的审查结果；同一次运行中重新审查的文件会覆盖原有记录
+type Run struct {
+	ID            uint64       `json:"id"`
+	StartedAt     time.Time    `json:"started_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	Provider      string       `json:"provider"`
+	Model         string       `json:"model"`
+	PromptVersion string       `json:"prompt_version"`
+	Head          string       `json:"head,omitempty"`
+	Branch        string       `json:"branch,omitempty"`
+	Files         []FileRecord `json:"files"`
+}
+
+// FileRecord 是某次运行中单个文件的审查结果。DiffHash 是暂存区 diff 的 SHA-256，
+// 用于判断两次审查针对的是否是同一份代码；Usage 是审查该文件消耗的 token 与费用。
+type FileRecord struct {
+	File       string           `json:"file"`
+	DiffHash   string           `json:"diff_hash"`
+	Diff       string           `json:"diff"`
+	Markdown   string           `json:"markdown"`
+	Findings   []review.Finding `json:"findings"`
+	Usage      ai.Usage         `json:"usage"`
+	ReviewedAt time.Time        `json:"reviewed_at"`
+}
+
+// FindingCount 返回运行中所有文件的问题总数。
+func (r Run) FindingCount() int {
+	n := 0
+	for _, f := range r.Files {
+		n += len(f.Findings)
+	}
+	return n
+}
+
+// Usage 返回运行中所有文件审查的用量之和。同一文件重新审查时只保留最后一次的用量，
+// 因此可能小于实际消耗；追问对话的用量也不计入。
+func (r Run) Usage() ai.Usage {
+	var u ai.Usage
+	for _, f := range r.Files {
+		u.Add(f.Usage)
+	}
+	return u
+}
+
+// File 返回运行中 file 的审查记录。
+func (r Run) File(file string) (FileRecord, bool) {
+	for _, f := range r.Files {
+		if f.File == file {
+			return f, true
+		}
+	}
+	return FileRecord{}, false
+}
+
+// DiffHash 计算 diff 的 SHA-256。
+func DiffHash(diff string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(diff)))
+}
+
+// Store 是审查历史数据库。每次读写都单独打开并关闭数据库文件，避免 TUI 运行期间长时间持有文件锁。
+type Store struct {
+	path string
+}
+
+// Open 返回当前仓库的审查历史。
+func Open() (*Store, error) {
+	dir, err := gitops.GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: filepath.Join(dir, dbFile)}, nil
+}
+
+// update 在读写事务中执行 fn，runs bucket 不存在时自动创建。
+func (s *Store) update(fn func(b *bolt.Bucket) error) error {
+	db, err := bolt.Open(s.path, 0o644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return fmt.Errorf("打开审查历史 %s 失败：%w", s.path, err)
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(runsBucket)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+// view 在只读事务中执行 fn；数据库或 bucket 尚不存在时 b 为 nil。
+func (s *Store) view(fn func(b *bolt.Bucket) error) error {
+	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		return fn(nil)
+	}
+
+	db, err := bolt.Open(s.path, 0o644, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("打开审查历史 %s 失败：%w", s.path, err)
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(runsBucket))
+	})
+}
+
+// List 返回最近的 limit 条运行记录（limit <= 0 表示全部），按编号从新到旧排列。
+func (s *Store) List(limit int) ([]Run, error) {
+	var runs []Run
+	err := s.view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var run Run
+			if err := json.Unmarshal(v, &run); err != nil {
+				return fmt.Errorf("解析审查记录 %d 失败：%w", binary.BigEndian.Uint64(k), err)
+			}
+			runs = append(runs, run)
+			if limit > 0 && len(runs) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return runs, err
+}
+
+// RunsWithFile 返回包含 file 审查结果的最近 limit 条运行记录，按编号从新到旧排列。
+func (s *Store) RunsWithFile(file string, limit int) ([]Run, error) {
+	all, err := s.List(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for _, run := range all {
+		if _, ok := run.File(file); ok {
+			runs = append(runs, run)
+			if limit > 0 && len(runs) >= limit {
+				break
+			}
+		}
+	}
+	return runs, nil
+}
+
+// Get 返回编号为 id 的运行记录，不存在时返回 ErrNotFound。
+func (s *Store) Get(id uint64) (*Run, error) {
+	var run *Run
+	err := s.view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return ErrNotFound
+		}
+		v := b.Get(itob(id))
+		if v == nil {
+			return ErrNotFound
+		}
+		run = &Run{}
+		return json.Unmarshal(v, run)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// save 写入运行记录；run.ID 为 0 时分配新的编号。
+func (s *Store) save(run *Run) error {
+	return s.update(func(b *bolt.Bucket) error {
+		if run.ID == 0 {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			run.ID = id
+		}
+
+		data, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		return b.Put(itob(run.ID), data)
+	})
+}
+
+// Recorder 把一次 TUI / 命令行运行中的审查结果写入历史，实现 review.Recorder。
+//
+// 第一个文件审查完成时才创建运行记录，因此没有审查任何文件的运行不会留下空记录。
+// 每个文件完成后立即写入，即使程序中途退出也能保留已完成的部分。
+type Recorder struct {
+	store *Store
+
+	mu  sync.Mutex
+	run Run
+}
+
+// NewRecorder 为一次新的运行创建 Recorder。identity 为实际使用的提供商与模型。
+func (s *Store) NewRecorder(identity ai.Identity) *Recorder {
+	head, branch, _ := gitops.GetHead()
+	return &Recorder{
+		store: s,
+		run: Run{
+			StartedAt:     time.Now(),
+			Provider:      identity.Provider,
+			Model:         identity.Model,
+			PromptVersion: review.PromptVersion,
+			Head:          head,
+			Branch:        branch,
+		},
+	}
+}
+
+// Record 实现 review.Recorder：新增或覆盖该文件的审查结果并写回数据库。
+func (r *Recorder) Record(rev *review.FileReview) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := FileRecord{
+		File:       rev.File,
+		DiffHash:   DiffHash(rev.Diff),
+		Diff:       rev.Diff,
+		Markdown:   rev.Markdown,
+		Findings:   rev.Findings,
+		Usage:      rev.Usage,
+		ReviewedAt: time.Now(),
+	}
+
+	replaced := false
+	for i := range r.run.Files {
+		if r.run.Files[i].File == rev.File {
+			r.run.Files[i] = rec
+			replaced = true
+		}
+	}
+	if !replaced {
+		r.run.Files = append(r.run.Files, rec)
+		sort.Slice(r.run.Files, func(i, j int) bool { return r.run.Files[i].File < r.run.Files[j].File })
+	}
+	r.run.UpdatedAt = rec.ReviewedAt
+
+	return r.store.save(&r.run)
+}
+
+// itob 把运行编号编码为 8 字节大端序，使 bbolt 中的 key 顺序与编号顺序一致。
+func itob(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
@@ -0,0 +1,134 @@
+// Package policy 按文件路径限制代码可以发送给哪些 LLM 提供商。
+//
+// 策略由配置中的 egress.rules 定义，按顺序匹配，第一条匹配的规则生效；
+// 没有匹配任何规则的文件可以发送给任意提供商。Provider 包装一个 ai.LLMProvider，
+// 在请求发送前检查请求涉及的每个文件。
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+)
+
+// never 是规则中表示"不发送给任何提供商"的特殊提供商名称。
+const never = "never"
+
+// ErrDenied 表示外发策略不允许把某个文件发送给当前提供商。
+var ErrDenied = errors.New("外发策略不允许发送")
+
+// rule 是一条编译后的策略规则。
+type rule struct {
+	patterns  []string
+	providers []string
+	never     bool
+}
+
+// Policy 是按路径的外发策略。nil 表示没有任何限制。
+type Policy struct {
+	rules []rule
+}
+
+// New 根据配置创建 Policy；没有任何规则时返回 nil。路径 glob 无效时返回错误。
+func New(cfg config.EgressConfig) (*Policy, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+
+	p := &Policy{}
+	for i, r := range cfg.Rules {
+		if len(r.Paths) == 0 {
+			return nil, fmt.Errorf("外发策略第 %d 条规则没有配置 paths", i+1)
+		}
+		if len(r.Providers) == 0 {
+			return nil, fmt.Errorf("外发策略第 %d 条规则没有配置 providers", i+1)
+		}
+
+		var compiled rule
+		for _, pat := range r.Paths {
+			pat = strings.TrimPrefix(strings.TrimSpace(pat), "./")
+			// 以 "/" 结尾的路径表示整个目录
+			if strings.HasSuffix(pat, "/") {
+				pat += "**"
+			}
+			if _, err := path.Match(pat, ""); err != nil {
+				return nil, fmt.Errorf("外发策略第 %d 条规则的路径 %q 无效：%w", i+1, pat, err)
+			}
+			compiled.patterns = append(compiled.patterns, pat)
+		}
+		for _, name := range r.Providers {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == never {
+				compiled.never = true
+			}
+			compiled.providers = append(compiled.providers, name)
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// Check 检查 file（相对仓库根目录的路径）是否允许发送给 provider，不允许时返回包装了 ErrDenied 的错误。
+func (p *Policy) Check(provider, file string) error {
+	if p == nil {
+		return nil
+	}
+
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	for _, r := range p.rules {
+		pat, ok := r.match(file)
+		if !ok {
+			continue
+		}
+		if r.never {
+			return fmt.Errorf("%w：%s 匹配 %q，不允许发送给任何提供商", ErrDenied, file, pat)
+		}
+		for _, allowed := range r.providers {
+			if allowed == provider || allowed == "*" {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w：%s 匹配 %q，只允许发送给 %s（当前为 %s）",
+			ErrDenied, file, pat, strings.Join(r.providers, " / "), provider)
+	}
+	return nil
+}
+
+// match 返回 r 中第一个匹配 file 的路径 glob。
+func (r rule) match(file string) (string, bool) {
+	for _, pat := range r.patterns {
+		if matchGlob(pat, file) {
+			return pat, true
+		}
+	}
+	return "", false
+}
+
+// matchGlob 判断 name 是否匹配 pattern。每一段按 path.Match 匹配，"**" 匹配任意多段（包括零段）。
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pats, names []string) bool {
+	for len(pats) > 0 {
+		if pats[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(pats[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pats[0], names[0]); !ok {
+			return false
+		}
+		pats, names = pats[1:], names[1:]
+	}
+	return len(names) == 0
+}
@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"main.go", "main.go", true},
+		{"main.go", "cmd/main.go", false},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false}, // * 不跨越目录
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/sub/main.go", false},
+
+		{"**", "a/b/c.go", true},
+		{"**/*.go", "main.go", true}, // ** 可以匹配零段
+		{"**/*.go", "a/b/c.go", true},
+		{"**/*_secret.go", "internal/db/db_secret.go", true},
+		{"**/*_secret.go", "internal/db/secret.go", false},
+		{"secrets/**", "secrets", true},
+		{"secrets/**", "secrets/prod/key.pem", true},
+		{"secrets/**", "app/secrets/key.pem", false},
+		{"secrets/**", "secrets-old/key.pem", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+		{"a/**/b/*.go", "a/x/b/y/c.go", false},
+		{"**/testdata/**", "pkg/testdata/in.txt", true},
+		{"**/testdata/**", "pkg/testdata", true},
+		{"**/testdata/**", "pkg/testdatas/in.txt", false},
+		{"internal/[a-c]*/x.go", "internal/billing/x.go", true},
+		{"internal/[a-c]*/x.go", "internal/user/x.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v，期望 %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPatterns(t *testing.T) {
+	p, err := New(config.EgressConfig{Rules: []config.EgressRule{
+		{Paths: []string{"internal/billing/", "./vendor/"}, Providers: []string{"onprem"}},
+	}})
+	if err != nil {
+		t.Fatalf("New 返回错误：%v", err)
+	}
+
+	want := []string{"internal/billing/**", "vendor/**"}
+	got := p.rules[0].patterns
+	if len(got) != len(want) {
+		t.Fatalf("patterns = %q，期望 %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("patterns[%d] = %q，期望 %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []config.EgressRule
+	}{
+		{"no paths", []config.EgressRule{{Providers: []string{"openai"}}}},
+		{"no providers", []config.EgressRule{{Paths: []string{"a/"}}}},
+		{"bad glob", []config.EgressRule{{Paths: []string{"a/[b"}, Providers: []string{"openai"}}}},
+	}
+	for _, tt := range tests {
+		if _, err := New(config.EgressConfig{Rules: tt.rules}); err == nil {
+			t.Errorf("%s：New 应当返回错误", tt.name)
+		}
+	}
+
+	p, err := New(config.EgressConfig{})
+	if p != nil || err != nil {
+		t.Errorf("没有规则时 New = %v, %v，期望 nil, nil", p, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := New(config.EgressConfig{Rules: []config.EgressRule{
+		{Paths: []string{"internal/billing/"}, Providers: []string{"OnPrem"}},
+		{Paths: []string{"secrets/**", "**/*_secret.go"}, Providers: []string{"never"}},
+		{Paths: []string{"docs/**"}, Providers: []string{"*"}},
+		{Paths: []string{"**/*.go"}, Providers: []string{"openai", "onprem"}},
+	}})
+	if err != nil {
+		t.Fatalf("New 返回错误：%v", err)
+	}
+
+	tests := []struct {
+		provider string
+		file     string
+		allowed  bool
+	}{
+		{"onprem", "internal/billing/invoice.go", true},
+		{" ONPREM ", "internal/billing/invoice.go", true},
+		{"openai", "internal/billing/invoice.go", false}, // 第一条匹配的规则生效
+		{"onprem", "secrets/prod.yaml", false},
+		{"onprem", "internal/db/db_secret.go", false},
+		{"anthropic", "docs/guide.md", true},
+		{"openai", "cmd/root.go", true},
+		{"anthropic", "cmd/root.go", false},
+		{"anthropic", "Makefile", true}, // 没有匹配任何规则
+	}
+	for _, tt := range tests {
+		err := p.Check(tt.provider, tt.file)
+		if tt.allowed && err != nil {
+			t.Errorf("Check(%q, %q) = %v，期望允许", tt.provider, tt.file, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrDenied) {
+			t.Errorf("Check(%q, %q) = %v，期望 ErrDenied", tt.provider, tt.file, err)
+		}
+	}
+
+	var none *Policy
+	if err := none.Check("openai", "secrets/prod.yaml"); err != nil {
+		t.Errorf("nil Policy 的 Check = %v，期望 nil", err)
+	}
+}
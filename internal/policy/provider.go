@@ -0,0 +1,33 @@
+package policy
+
+import (
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+)
+
+// Provider 包装一个 ai.LLMProvider：请求涉及的任意文件不允许发送给被包装的提供商时，
+// 直接返回 ErrDenied，不发送请求。不带 Files 的请求不受限制。
+type Provider struct {
+	ai.Middleware
+	policy *Policy
+}
+
+// Wrap 用 policy 包装 provider；policy 为 nil 时直接返回 provider。
+func Wrap(provider ai.LLMProvider, policy *Policy) ai.LLMProvider {
+	if policy == nil {
+		return provider
+	}
+	p := &Provider{policy: policy}
+	p.Middleware = ai.Middleware{Next: provider, SendFunc: p.send}
+	return p
+}
+
+// send 是嵌入的 ai.Middleware 的 SendFunc：检查 req.Files 中的每个文件后转发。
+func (p *Provider) send(req ai.Request) (string, ai.Usage, error) {
+	name := p.Identity().Provider
+	for _, f := range req.Files {
+		if err := p.policy.Check(name, f); err != nil {
+			return "", ai.Usage{}, err
+		}
+	}
+	return ai.Send(p.Next, req)
+}
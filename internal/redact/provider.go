@@ -0,0 +1,36 @@
+package redact
+
+import (
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+)
+
+// Provider 包装一个 ai.LLMProvider：每次请求发送前先对 prompt / 对话消息脱敏，
+// block 模式下发现密钥时直接返回 ErrBlocked，不发送请求。
+type Provider struct {
+	ai.Middleware
+	redactor *Redactor
+}
+
+// Wrap 用 redactor 包装 provider；redactor 为 nil 或处于 ModeOff 时直接返回 provider。
+func Wrap(provider ai.LLMProvider, redactor *Redactor) ai.LLMProvider {
+	if redactor == nil || redactor.Mode() == ModeOff {
+		return provider
+	}
+	p := &Provider{redactor: redactor}
+	p.Middleware = ai.Middleware{Next: provider, SendFunc: p.send}
+	return p
+}
+
+// send 是嵌入的 ai.Middleware 的 SendFunc：逐条消息检查并脱敏后转发。
+func (p *Provider) send(req ai.Request) (string, ai.Usage, error) {
+	redacted := make([]ai.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		if err := p.redactor.Check(m.Content); err != nil {
+			return "", ai.Usage{}, err
+		}
+		redacted[i] = ai.Message{Role: m.Role}
+		redacted[i].Content, _ = p.redactor.Redact(m.Content)
+	}
+	req.Messages = redacted
+	return ai.Send(p.Next, req)
+}
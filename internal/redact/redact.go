@@ -0,0 +1,253 @@
+// Package redact 在代码发送给 LLM 之前查找其中的密钥并替换为占位符。
+//
+// 检测由三部分组成：内置的常见密钥格式（云服务密钥、私钥、JWT、.env 中的密码等）、
+// 配置中追加的正则，以及可选的高熵字符串检测。Provider 包装一个 ai.LLMProvider，
+// 保证经过它的每次请求都先经过脱敏（或在 block 模式下被拒绝）。
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+)
+
+// Mode 是发现密钥时的处理方式。
+type Mode string
+
+const (
+	// ModeRedact 把密钥替换为占位符后照常发送。
+	ModeRedact Mode = "redact"
+	// ModeBlock 发现密钥时拒绝发送整个请求。
+	ModeBlock Mode = "block"
+	// ModeOff 关闭脱敏。
+	ModeOff Mode = "off"
+)
+
+// ErrBlocked 表示 block 模式下因发现密钥而拒绝发送。
+var ErrBlocked = errors.New("发现疑似密钥，已拒绝发送")
+
+// entropyName 是高熵字符串检测在报告与占位符中使用的名称。
+const entropyName = "high-entropy"
+
+// Match 是一处被识别为密钥的内容。Preview 只保留开头几个字符，便于在本地报告中辨认而不泄露完整内容。
+type Match struct {
+	Name    string
+	Preview string
+}
+
+// detector 是一条密钥匹配规则。正则中有捕获组时只替换第一个捕获组。
+type detector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// builtinDetectors 是内置的常见密钥格式。
+var builtinDetectors = []detector{
+	{"private-key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b|\bgithub_pat_[A-Za-z0-9_]{22,}\b`)},
+	{"openai-key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[0-9A-Za-z]{16,}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	// 代码中以字符串字面量赋值的密码、token 等：password := "..."、"api_key": "..."
+	{"secret-assignment", regexp.MustCompile(`(?i)(?:password|passwd|secret|token|api_?key|access_?key|credential)[\w-]*["']?\s*[:=]+\s*["'` + "`" + `]([^"'` + "`" + `\s]{8,})["'` + "`" + `]`)},
+	// .env / shell 中的赋值：DB_PASSWORD=...、export API_TOKEN=...（diff 中行首可能带 +/-/空格）。
+	// 以 $ 开头的值是对其他变量的引用（${DB_PASSWORD}），不是密钥本身。
+	{"env-secret", regexp.MustCompile(`(?m)^[+\- ]?\s*(?:export\s+)?[A-Z0-9_]*(?:PASSWORD|SECRET|TOKEN|API_?KEY|ACCESS_KEY|PRIVATE_KEY)[A-Z0-9_]*\s*=\s*([^\s"'#$][^\s"'#]{7,})`)},
+}
+
+// entropyCandidate 是高熵检测的候选字符串：20 个以上的字母、数字与 base64url 字符。
+// 不包含 "/"，避免把 import 路径、URL 当作候选。
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9_=-]{20,}`)
+
+// Redactor 按配置查找并替换密钥。零值不可用，请使用 New 创建。
+type Redactor struct {
+	mode      Mode
+	entropy   bool
+	detectors []detector
+}
+
+// New 根据配置创建 Redactor。mode 为空时使用 ModeRedact；自定义正则无法编译时返回错误。
+func New(cfg config.RedactionConfig) (*Redactor, error) {
+	mode := Mode(strings.ToLower(strings.TrimSpace(cfg.Mode)))
+	switch mode {
+	case "":
+		mode = ModeRedact
+	case ModeRedact, ModeBlock, ModeOff:
+	default:
+		return nil, fmt.Errorf("未知的脱敏模式 %q（可选 redact / block / off）", cfg.Mode)
+	}
+
+	r := &Redactor{
+		mode:      mode,
+		entropy:   cfg.Entropy,
+		detectors: append([]detector(nil), builtinDetectors...),
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏规则 %q 的正则无效：%w", p.Name, err)
+		}
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			name = "custom"
+		}
+		r.detectors = append(r.detectors, detector{name: name, re: re})
+	}
+	return r, nil
+}
+
+// Mode 返回发现密钥时的处理方式。
+func (r *Redactor) Mode() Mode {
+	return r.mode
+}
+
+// span 是 text 中待替换的一段。
+type span struct {
+	start, end int
+	name       string
+}
+
+// Redact 返回把 text 中的密钥替换为 "[REDACTED:规则名]" 之后的内容，以及识别出的密钥。
+// 多条规则重叠时保留先匹配到的（内置规则优先于自定义规则，二者都优先于高熵检测）。
+// ModeOff 时原样返回。
+func (r *Redactor) Redact(text string) (string, []Match) {
+	if r == nil || r.mode == ModeOff {
+		return text, nil
+	}
+
+	var spans []span
+	add := func(s span) {
+		for _, o := range spans {
+			if s.start < o.end && o.start < s.end {
+				return
+			}
+		}
+		spans = append(spans, s)
+	}
+
+	for _, d := range r.detectors {
+		for _, loc := range d.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			add(span{start, end, d.name})
+		}
+	}
+	if r.entropy {
+		for _, loc := range entropyCandidate.FindAllStringIndex(text, -1) {
+			if !highEntropy(text[loc[0]:loc[1]]) {
+				continue
+			}
+			add(span{loc[0], loc[1], entropyName})
+		}
+	}
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var (
+		b       strings.Builder
+		matches []Match
+		last    int
+	)
+	for _, s := range spans {
+		b.WriteString(text[last:s.start])
+		b.WriteString("[REDACTED:" + s.name + "]")
+		matches = append(matches, Match{Name: s.name, Preview: preview(text[s.start:s.end])})
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+// Check 在 block 模式下发现密钥时返回 ErrBlocked，说明发现了哪些密钥；其他模式总是返回 nil。
+func (r *Redactor) Check(text string) error {
+	if r == nil || r.mode != ModeBlock {
+		return nil
+	}
+	if _, matches := r.Redact(text); len(matches) > 0 {
+		return fmt.Errorf("%w：%s", ErrBlocked, Summary(matches))
+	}
+	return nil
+}
+
+// Summary 把识别出的密钥汇总为 "aws-access-key（AKIA…）、jwt（eyJh…）"。
+func Summary(matches []Match) string {
+	parts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		parts = append(parts, fmt.Sprintf("%s（%s）", m.Name, m.Preview))
+	}
+	return strings.Join(parts, "、")
+}
+
+// preview 只保留密钥开头的 4 个字符；过短的密钥完全隐藏。
+func preview(secret string) string {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < 12 {
+		return "…"
+	}
+	return secret[:4] + "…"
+}
+
+// highEntropy 判断候选字符串是否像随机生成的密钥。
+//
+//   - 纯十六进制且长度 >= 32：哈希、随机 token 等，香农熵 >= 3.0
+//   - 其他字符串需要同时包含大写、小写与至少两个数字（排除普通的驼峰标识符），
+//     且香农熵不低于该长度下理论最大值的 85%
+func highEntropy(s string) bool {
+	if isHex(s) {
+		return len(s) >= 32 && shannon(s) >= 3.0
+	}
+
+	var upper, lower, digits int
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+		case c >= 'a' && c <= 'z':
+			lower++
+		case c >= '0' && c <= '9':
+			digits++
+		}
+	}
+	if upper == 0 || lower == 0 || digits < 2 {
+		return false
+	}
+	return shannon(s) >= 0.85*math.Log2(math.Min(float64(len(s)), 64))
+}
+
+// isHex 判断 s 是否只由十六进制字符组成（允许 "-" 分隔，如 UUID）。
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// shannon 返回 s 每个字符的香农熵（比特）。
+func shannon(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,153 @@
+package review
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/analysis"
+	"github.com/GuLuGuLuGit/review-go/internal/apidiff"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// SourceAPI 是导出 API 检查（见 checkAPI）给出的诊断与问题的来源名称。
+const SourceAPI = "apidiff"
+
+// apiNote 在提示词中跟在静态分析诊断之后，说明 apidiff 诊断的含义以及希望 LLM 补充的内容。
+const apiNote = "\n其中来源为 apidiff 的是与审查起点相比、导出 API 的不兼容变化，会导致依赖该包的下游代码无法编译。" +
+	"请说明受影响的调用方式，判断这次变化是否必要，并给出保持兼容的做法（例如保留旧的函数或字段并标记 Deprecated）。\n"
+
+// checkAPI 比较 files 所在的包在审查起点与最新版本（暂存区或 Options.Head）之间的导出 API，
+// 把不兼容的变化转换为 apidiff 诊断，按文件分组返回，只保留 files 中的文件。
+//
+// 修改或新增接口方法的诊断位于新版本的声明处；被删除的标识符位于其原来所在的文件，行号为 0，
+// 原来的位置写在诊断中。main 包、internal 目录下的包（无法被其他模块导入）与起点中不存在的包不做检查，
+// 任一版本无法解析时也跳过该包。
+func (r *Runner) checkAPI(files []string) map[string][]analysis.Diagnostic {
+	result := make(map[string][]analysis.Diagnostic)
+	if !r.opts.API {
+		return result
+	}
+
+	wanted := make(map[string]bool, len(files))
+	var dirs []string
+	for _, f := range files {
+		wanted[f] = true
+		if !strings.HasSuffix(f, ".go") || strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		dir := path.Dir(f)
+		if !internalDir(dir) && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	base, head := r.Range()
+	if head == "" {
+		base = "HEAD"
+	}
+	for _, dir := range dirs {
+		before, err := r.loadPackage(base, dir)
+		if err != nil || len(before.Objects) == 0 || before.Name == "main" {
+			continue
+		}
+		after, err := r.loadPackage(head, dir)
+		if err != nil || after.Name == "main" {
+			continue
+		}
+
+		for _, c := range apidiff.Compare(before, after) {
+			d := analysis.Diagnostic{Tool: SourceAPI, Message: c.Message}
+			if c.New != nil {
+				d.File, d.Line = c.New.File, c.New.Line
+			} else {
+				d.File = c.Old.File
+				d.Message += fmt.Sprintf("（原位于 %s 第 %d 行）", c.Old.File, c.Old.Line)
+			}
+			if wanted[d.File] {
+				result[d.File] = append(result[d.File], d)
+			}
+		}
+	}
+	for _, diags := range result {
+		sort.SliceStable(diags, func(i, j int) bool { return diags[i].Line < diags[j].Line })
+	}
+	return result
+}
+
+// SkippedReviews 对因 Options.MaxFiles 限制而不审查的文件（Triage.Skipped）只做导出 API 检查，
+// 为其中有不兼容变化的文件各返回一个不经过 LLM 的 FileReview，key 为文件路径（分组审查时也按文件）。
+// 这些问题与审查的范围无关，下游代码同样会无法编译，因此不能随文件一起跳过。
+func (r *Runner) SkippedReviews(skipped []string) []*FileReview {
+	if r == nil || len(skipped) == 0 {
+		return nil
+	}
+
+	diags := r.checkAPI(skipped)
+	var reviews []*FileReview
+	for _, f := range skipped {
+		diff, _ := r.fileDiff(f)
+		note := "_风险评分较低，按 --max-files 未发送给 LLM 审查；以下只列出导出 API 检查发现的问题。_"
+		if rev := r.diagnosticsReview(f, diff, diags[f], nil, note); rev != nil {
+			reviews = append(reviews, rev)
+		}
+	}
+	return reviews
+}
+
+// loadPackage 读取并解析目录 dir 中包的导出 API：rev 为空时读取暂存区，否则读取提交 rev。
+// 目录在 rev 中不存在时返回空的包。
+func (r *Runner) loadPackage(rev, dir string) (*apidiff.Package, error) {
+	var (
+		names []string
+		err   error
+	)
+	if rev == "" {
+		names, err = gitops.GetStagedDirFiles(dir)
+	} else {
+		names, err = gitops.GetDirFilesAt(rev, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]string)
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		var content string
+		if rev == "" {
+			content, err = gitops.GetStagedFileContent(name)
+		} else {
+			content, err = gitops.GetFileContentAt(rev, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = content
+	}
+	return apidiff.Parse(sources)
+}
+
+// internalDir 报告 dir 是否位于 internal 目录下：这类包只能被同一模块内的代码导入，导出 API 的变化不影响下游。
+func internalDir(dir string) bool {
+	for _, part := range strings.Split(dir, "/") {
+		if part == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAPIDiagnostics 报告 diags 中是否包含 apidiff 诊断。
+func hasAPIDiagnostics(diags []analysis.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Tool == SourceAPI {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// maxChangeDiffBytes 是一次请求中发送的 diff 总大小上限；超出后其余文件只列出文件名，避免超出模型的上下文长度。
+const maxChangeDiffBytes = 60_000
+
+// Draft 是根据一组变更整体生成的文本，例如提交信息或 PR 描述。
+//
+// - Message: 生成的文本
+// - Files: 发送给 LLM 的文件
+// - Denied: 外发策略不允许发送给当前提供商、因而没有包含在请求中的文件
+// - Usage: 本次请求消耗的 token 与费用
+type Draft struct {
+	Message string
+	Files   []string
+	Denied  []string
+	Usage   ai.Usage
+}
+
+// changeSet 是一组将要整体发送给 LLM 的变更（不限于 .go 文件）。
+//
+// - files: 包含在请求中的文件
+// - denied: 外发策略不允许发送给当前提供商的文件
+// - diff: files 的 diff 拼接，超过 maxChangeDiffBytes 的部分省略
+// - omitted: 因 diff 过长只列出文件名的文件
+type changeSet struct {
+	files   []string
+	denied  []string
+	diff    string
+	omitted []string
+}
+
+// collectChanges 收集暂存区（或 Options.Base 与 Head 之间）的全部变更，按外发策略过滤掉不能发送的文件。
+func (r *Runner) collectChanges() (*changeSet, error) {
+	var (
+		base, head = r.Range()
+		where      = "暂存区中"
+		files      []string
+		err        error
+	)
+	if head != "" {
+		where = fmt.Sprintf("%s..%s 之间", base, head)
+		files, err = gitops.GetRangeFiles(base, head)
+	} else {
+		files, err = gitops.GetStagedFiles()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取变更文件失败：%w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s没有变更", where)
+	}
+
+	cs := &changeSet{}
+	provider := r.Identity().Provider
+	var b strings.Builder
+	for _, f := range files {
+		if r.opts.Policy.Check(provider, f) != nil {
+			cs.denied = append(cs.denied, f)
+			continue
+		}
+		cs.files = append(cs.files, f)
+
+		var diff string
+		if head != "" {
+			diff, err = gitops.GetFileRangeDiffWithContext(base, head, f, 3)
+		} else {
+			diff, err = gitops.GetFileStagedDiffWithContext(f, 3)
+		}
+		if err != nil || b.Len()+len(diff) > maxChangeDiffBytes {
+			cs.omitted = append(cs.omitted, f)
+			continue
+		}
+		b.WriteString(diff + "\n")
+	}
+	if len(cs.files) == 0 {
+		return nil, fmt.Errorf("%s的 %d 个文件都不允许发送给 %s", where, len(cs.denied), provider)
+	}
+
+	cs.diff = strings.TrimRight(b.String(), "\n")
+	return cs, nil
+}
+
+// writePrompt 把变更的文件列表与 diff 追加到提示词中。
+func (cs *changeSet) writePrompt(b *strings.Builder) {
+	b.WriteString("\n变更的文件：\n")
+	for _, f := range cs.files {
+		fmt.Fprintf(b, "- %s\n", f)
+	}
+	if len(cs.omitted) > 0 {
+		fmt.Fprintf(b, "\n以下文件的 diff 过长或无法读取，已省略，请根据文件名推断：%s\n", strings.Join(cs.omitted, "、"))
+	}
+	if cs.diff != "" {
+		fmt.Fprintf(b, "\n```diff\n%s\n```\n", cs.diff)
+	}
+}
+
+// sendChanges 把 prompt 连同 cs 中的文件一起发送给 LLM，返回的 Draft 中 Message 为原始回复。
+func (r *Runner) sendChanges(cs *changeSet, prompt string) (*Draft, error) {
+	reply, used, err := ai.Send(r.provider, ai.Request{
+		Messages: []ai.Message{{Role: ai.RoleUser, Content: prompt}},
+		Files:    cs.files,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Draft{Message: reply, Files: cs.files, Denied: cs.denied, Usage: used}, nil
+}
@@ -0,0 +1,97 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+const chatSystemPrompt = `你是一名资深 Golang 专家，刚刚完成了对下面这个文件暂存区变更的代码审查。
+接下来用户会就这次审查继续提问，例如追问某个问题的原因，或者请你给出修改后的代码。
+请基于下面的 diff 与审查报告作答，使用 Markdown 格式，回答要具体、可操作；不确定时请直接说明。`
+
+const overallChatSystemPrompt = `你是一名资深 Golang 专家，刚刚完成了对下面这组变更的整体审查，重点是跨文件的问题。
+接下来用户会就这次审查继续提问，例如追问某个问题的原因，或者请你给出需要同步修改的代码。
+请基于下面的 diff 与审查报告作答，使用 Markdown 格式，回答要具体、可操作；不确定时请直接说明。`
+
+// ChatSeed 返回针对某个文件审查结果开启追问对话时的初始消息：
+// 一条包含该文件 diff 与审查报告的系统消息。整体审查与分组审查的结果包含其中全部文件的 diff。
+func ChatSeed(rev *FileReview) []ai.Message {
+	var b strings.Builder
+	if rev.File == OverallFile {
+		b.WriteString(overallChatSystemPrompt)
+	} else {
+		b.WriteString(chatSystemPrompt)
+	}
+	files := rev.File
+	if len(rev.Files) > 0 {
+		files = strings.Join(rev.Files, "、")
+	}
+	fmt.Fprintf(&b, "\n\n文件：%s\n\n```diff\n%s\n```\n\n审查报告：\n\n%s", files, rev.Diff, rev.Markdown)
+	if findings := FindingsMarkdown(rev.File, rev.Findings, -1); findings != "" {
+		b.WriteString("\n\n" + findings)
+	}
+
+	return []ai.Message{{Role: ai.RoleSystem, Content: b.String()}}
+}
+
+// Chat 发送一轮关于 rev 的追问对话，messages 应以 ChatSeed(rev) 的结果开头。
+func (r *Runner) Chat(rev *FileReview, messages []ai.Message) (string, error) {
+	if r == nil || r.provider == nil {
+		return "", fmt.Errorf("LLM Provider 未初始化")
+	}
+	files := []string{rev.File}
+	if len(rev.Files) > 0 {
+		files = rev.Files
+	}
+	reply, _, err := ai.Send(r.provider, ai.Request{Messages: messages, Files: files})
+	return reply, err
+}
+
+// SaveTranscript 把文件的审查报告连同追问对话记录一起保存为 Markdown，返回写入的路径。
+//
+// 文件保存在仓库状态目录下的 transcripts/ 中，文件名由被审查文件路径与时间戳组成，
+// 多次保存不会互相覆盖。
+func SaveTranscript(rev *FileReview, messages []ai.Message) (string, error) {
+	stateDir, err := gitops.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(stateDir, "transcripts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建目录 %s 失败: %w", dir, err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.md", strings.ReplaceAll(rev.File, "/", "_"), now.Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s 审查记录\n\n_保存时间：%s_\n\n", rev.File, now.Format(time.RFC3339))
+	b.WriteString(rev.Markdown)
+	if findings := FindingsMarkdown(rev.File, rev.Findings, -1); findings != "" {
+		b.WriteString("\n\n" + findings)
+	}
+
+	b.WriteString("\n\n# 追问对话\n")
+	for _, msg := range messages {
+		switch msg.Role {
+		case ai.RoleUser:
+			fmt.Fprintf(&b, "\n## 提问\n\n%s\n", msg.Content)
+		case ai.RoleAssistant:
+			fmt.Fprintf(&b, "\n## 回答\n\n%s\n", msg.Content)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return "", fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+
+	return path, nil
+}
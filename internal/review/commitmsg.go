@@ -0,0 +1,99 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 提交信息的风格，见 config.CommitConfig。
+const (
+	CommitStyleConventional = "conventional"
+	CommitStylePlain        = "plain"
+)
+
+const commitSystemPrompt = `你是一名资深工程师，请根据下面暂存区中的全部变更为这次提交撰写一条提交信息。`
+
+// CommitMessage 根据暂存区中的全部变更生成提交信息，语言与风格见 Options.Commit。
+func (r *Runner) CommitMessage() (*Draft, error) {
+	if r == nil || r.provider == nil {
+		return nil, errors.New("LLM Provider 未初始化")
+	}
+
+	cfg := r.opts.Commit
+	switch cfg.Style {
+	case "", CommitStyleConventional, CommitStylePlain:
+	default:
+		return nil, fmt.Errorf("未知的提交信息风格 %q（可选 %s / %s）", cfg.Style, CommitStyleConventional, CommitStylePlain)
+	}
+
+	cs, err := r.collectChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	draft, err := r.sendChanges(cs, r.buildCommitPrompt(cs))
+	if err != nil {
+		return nil, fmt.Errorf("生成提交信息失败：%w", err)
+	}
+
+	if !r.opts.DryRun {
+		draft.Message = cleanReply(draft.Message)
+	}
+	if draft.Message == "" {
+		return nil, errors.New("生成提交信息失败：LLM 返回了空内容")
+	}
+	return draft, nil
+}
+
+// buildCommitPrompt 构造生成提交信息的提示词。
+func (r *Runner) buildCommitPrompt(cs *changeSet) string {
+	cfg := r.opts.Commit
+
+	var b strings.Builder
+	b.WriteString(commitSystemPrompt)
+	b.WriteString("\n\n要求：\n")
+	if cfg.Style == CommitStylePlain {
+		b.WriteString("- 标题用一句话概括这次提交做了什么，使用祈使语气，不要加 type 前缀\n")
+	} else {
+		b.WriteString("- 遵循 Conventional Commits 规范，标题格式为 <type>(<scope>): <subject>。" +
+			"type 取 feat、fix、refactor、perf、docs、test、build、ci、chore、style、revert 之一；" +
+			"scope 可选，取受影响的模块或包名；破坏性变更在 type 或 scope 之后加 \"!\"，并在正文末尾写 \"BREAKING CHANGE: <说明>\"\n")
+	}
+	if cfg.MaxSubjectLength > 0 {
+		fmt.Fprintf(&b, "- 标题不超过 %d 个字符，结尾不加句号\n", cfg.MaxSubjectLength)
+	}
+	if cfg.Body {
+		b.WriteString("- 标题之后空一行写正文，用简短的列表说明改动了什么以及为什么，不要逐个罗列文件；改动很小时可以省略正文\n")
+	} else {
+		b.WriteString("- 只写标题一行，不要正文\n")
+	}
+	if lang := strings.TrimSpace(cfg.Language); lang != "" {
+		fmt.Fprintf(&b, "- 使用 %s 撰写", lang)
+		if cfg.Style != CommitStylePlain {
+			b.WriteString("（type 与 scope 保持英文）")
+		}
+		b.WriteString("\n")
+	}
+	if s := strings.TrimSpace(cfg.Instructions); s != "" {
+		fmt.Fprintf(&b, "- %s\n", s)
+	}
+	b.WriteString("- 只输出提交信息本身，不要放在代码块中，也不要附加任何解释\n")
+
+	cs.writePrompt(&b)
+	return b.String()
+}
+
+// cleanReply 去掉模型有时仍会在整个回复外面加上的代码块围栏与首尾空白。
+func cleanReply(reply string) string {
+	msg := strings.TrimSpace(reply)
+	if strings.HasPrefix(msg, "```") {
+		if i := strings.Index(msg, "\n"); i >= 0 {
+			msg = msg[i+1:]
+		} else {
+			msg = ""
+		}
+		msg = strings.TrimSuffix(strings.TrimSpace(msg), "```")
+	}
+	return strings.TrimSpace(msg)
+}
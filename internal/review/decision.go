@@ -0,0 +1,278 @@
+package review
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// DecisionStatus 是用户对一条问题做出的处理决定。
+type DecisionStatus string
+
+const (
+	// DecisionAccepted 表示问题成立、会修复。
+	DecisionAccepted DecisionStatus = "accepted"
+	// DecisionDismissed 表示误报或无需关注；之后的运行中针对相同代码的该问题默认隐藏。
+	DecisionDismissed DecisionStatus = "dismissed"
+	// DecisionWontFix 表示问题成立但决定不修复。
+	DecisionWontFix DecisionStatus = "wontfix"
+)
+
+// Label 返回决定的中文名称。
+func (s DecisionStatus) Label() string {
+	switch s {
+	case DecisionAccepted:
+		return "已接受"
+	case DecisionDismissed:
+		return "已忽略"
+	case DecisionWontFix:
+		return "不修复"
+	default:
+		return string(s)
+	}
+}
+
+// Decision 是保存在本地状态文件中的一条决定。File / Line / Title 只用于人工查看状态文件。
+type Decision struct {
+	Status DecisionStatus `json:"status"`
+	Reason string         `json:"reason,omitempty"`
+	File   string         `json:"file"`
+	Line   int            `json:"line,omitempty"`
+	Title  string         `json:"title"`
+	At     time.Time      `json:"at"`
+}
+
+// decisionsFile 是决定在状态目录中的文件名。
+const decisionsFile = "decisions.json"
+
+// decisionsLockTimeout 是等待其他进程释放决定记录锁的最长时间；decisionsStaleLock 之前创建的锁文件
+// 视为崩溃的进程遗留下来的，直接删除。
+const (
+	decisionsLockTimeout = 3 * time.Second
+	decisionsStaleLock   = 30 * time.Second
+)
+
+// DecisionStore 以问题指纹为 key 保存用户的决定，持久化在 <git-common-dir>/review-go/decisions.json。
+//
+// TUI 在主循环中写入、在后台审查任务中读取，因此所有访问都加锁。TUI、Git 钩子与无界面模式可能同时运行，
+// 写入时持有锁文件（decisions.json.lock），先重新读取文件、合并本次的修改，再写临时文件并重命名，
+// 多个进程的决定不会互相覆盖，崩溃也不会留下写了一半的文件。
+type DecisionStore struct {
+	mu        sync.Mutex
+	path      string
+	decisions map[string]Decision
+}
+
+// LoadDecisions 读取当前仓库的决定记录，文件不存在时返回空记录。
+func LoadDecisions() (*DecisionStore, error) {
+	dir, err := gitops.GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DecisionStore{path: filepath.Join(dir, decisionsFile)}
+	if s.decisions, err = s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 返回指纹对应的决定。
+func (s *DecisionStore) Get(fingerprint string) (Decision, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.decisions[fingerprint]
+	return d, ok
+}
+
+// Set 记录（status 为空时清除）指纹对应的决定，并立即写回状态文件。
+// 写入前重新读取文件，其他进程在此期间保存的决定同时合并到内存中。
+func (s *DecisionStore) Set(fingerprint string, d Decision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	decisions, err := s.load()
+	if err != nil {
+		return err
+	}
+	if d.Status == "" {
+		delete(decisions, fingerprint)
+	} else {
+		decisions[fingerprint] = d
+	}
+
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化决定记录失败：%w", err)
+	}
+	if err := s.write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.decisions = decisions
+	return nil
+}
+
+// load 读取状态文件中的全部决定，文件不存在时返回空记录。
+func (s *DecisionStore) load() (map[string]Decision, error) {
+	decisions := make(map[string]Decision)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return decisions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败：%w", s.path, err)
+	}
+	if err := json.Unmarshal(data, &decisions); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败：%w", s.path, err)
+	}
+	return decisions, nil
+}
+
+// write 先把 data 写入同目录的临时文件再重命名为状态文件，避免读取方看到写了一半的文件。
+func (s *DecisionStore) write(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), decisionsFile+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败：%w", s.path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入 %s 失败：%w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入 %s 失败：%w", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入 %s 失败：%w", s.path, err)
+	}
+	return nil
+}
+
+// lock 创建锁文件，阻止其他进程同时修改决定记录，返回释放锁的函数。
+// 锁被占用时每隔一小段时间重试，超过 decisionsLockTimeout 仍未获得时返回错误；超过 decisionsStaleLock 的锁文件视为遗留并删除。
+func (s *DecisionStore) lock() (func(), error) {
+	path := s.path + ".lock"
+	deadline := time.Now().Add(decisionsLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("锁定决定记录失败：%w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > decisionsStaleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("锁定决定记录失败：%s 被其他进程占用", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Fingerprint 计算问题的稳定指纹，使同一段代码上的同一问题在多次运行之间得到相同的 key。
+// lines 为问题所在文件的全部内容（按行拆分）。
+//
+// 行号会随上方代码的增删而变化，因此不参与计算，改用该行去掉首尾空白后的内容。
+// 静态分析工具的消息是确定的，计入标题以区分同一行上的多条诊断。
+// LLM 每次给出的标题措辞并不固定，所以 LLM 问题（包括整体审查的问题）不计入标题，而是再计入上下相邻的非空行，
+// 避免对某一行的忽略连带隐藏同一文件中内容相同的其他行（如 "}"、"return err"）上的问题；
+// 该行本身过于常见（见 commonLine）时，仍然计入标题，只有措辞完全相同的问题才会被视为同一个。
+// 无法定位到代码行时退回使用标题。
+func Fingerprint(f Finding, lines []string) string {
+	lineText := ""
+	if f.Line > 0 && f.Line <= len(lines) {
+		lineText = strings.TrimSpace(lines[f.Line-1])
+	}
+
+	parts := []string{f.File, f.Source, lineText}
+	llm := f.Source == SourceLLM || f.Source == SourceOverall
+	if llm && lineText != "" {
+		parts = append(parts, neighbourLine(lines, f.Line-1, -1), neighbourLine(lines, f.Line-1, 1))
+	}
+	if !llm || lineText == "" || commonLine(lineText) {
+		parts = append(parts, strings.Join(strings.Fields(f.Title), " "))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("%x", sum[:12])
+}
+
+// neighbourLine 返回 lines 中第 idx 行（从 0 开始）向 step 方向最近的非空行（去掉首尾空白），没有时返回空字符串。
+func neighbourLine(lines []string, idx, step int) string {
+	for i := idx + step; i >= 0 && i < len(lines); i += step {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// commonLine 报告去掉首尾空白后的一行代码是否过于常见，不足以单独区分问题：
+// 例如 "}"、"return err"、"if err != nil {" 这类在同一文件中反复出现的短行。
+func commonLine(line string) bool {
+	return len(line) < 20
+}
+
+// applyDecisionsByFile 与 applyDecisions 相同，但 findings 可以分属不同文件（整体审查或分组审查），
+// 按各自文件的内容计算指纹。contents 为已经读取的文件内容，缺少的文件按审查范围读取。
+func (r *Runner) applyDecisionsByFile(findings []Finding, contents map[string]string) []Finding {
+	if contents == nil {
+		contents = make(map[string]string)
+	}
+
+	kept := findings[:0]
+	for _, f := range findings {
+		content, ok := contents[f.File]
+		if !ok {
+			content, _ = r.fileContent(f.File)
+			contents[f.File] = content
+		}
+		kept = append(kept, applyDecisions(r.decisions, []Finding{f}, content, !r.opts.ShowDismissed)...)
+	}
+	return kept
+}
+
+// applyDecisions 为 findings 计算指纹并附上已保存的决定；hideDismissed 为 true 时去掉已忽略的问题。
+// content 是文件在暂存区中的完整内容，用于取得问题所在行的代码。
+func applyDecisions(store *DecisionStore, findings []Finding, content string, hideDismissed bool) []Finding {
+	lines := strings.Split(content, "\n")
+
+	kept := findings[:0]
+	for _, f := range findings {
+		f.Fingerprint = Fingerprint(f, lines)
+
+		if store != nil {
+			if d, ok := store.Get(f.Fingerprint); ok {
+				if d.Status == DecisionDismissed && hideDismissed {
+					continue
+				}
+				f.Decision = &d
+			}
+		}
+		kept = append(kept, f)
+	}
+
+	return kept
+}
@@ -0,0 +1,280 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/cache"
+	"github.com/GuLuGuLuGit/review-go/internal/deps"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// SourceDeps 是依赖检查（见 DepsUnits）给出的问题的来源名称。
+const SourceDeps = "deps"
+
+const depsSystemPrompt = `你是一名资深 Golang 专家，熟悉 Go 模块与依赖管理。下面是一次变更中 go.mod 的 diff，
+以及 review-go 对依赖变化做的确定性检查结果：版本变化、大版本升级、replace 指令、离线漏洞库中的已知漏洞、
+模块缓存中的许可证与 go.sum 校验和。检查结果是准确的，请不要逐条复述，而是在此基础上解释：
+
+- 这次依赖变化的目的与影响范围，大版本升级可能带来的不兼容变化
+- 每个已知漏洞是否会影响本项目，应当升级到哪个版本
+- replace 指令与许可证变化是否适合合入，合入前需要确认什么
+- 检查结果之外值得注意的问题，例如引入了不必要的依赖、无故降级、间接依赖变为直接依赖
+
+请以 Markdown 格式输出：
+
+## 总体评价
+- 简要评价这次依赖变化。
+
+## 需要关注的依赖
+- 按风险从高到低说明需要关注的依赖及原因；没有时写“未发现”。
+
+## 建议
+- 合入前建议执行的操作，例如升级到修复版本、移除 replace、补充说明。
+
+在 Markdown 报告之后，请追加一个语言标记为 findings 的代码块，用 JSON 数组列出检查结果之外、你额外发现的问题，
+字段为 severity（high / medium / low / info）、line（go.mod 新版本中的行号，未知时为 0）、title（一句话概括）、detail（简要说明），例如：
+
+` + "```findings" + `
+[{"severity": "low", "line": 12, "title": "新增的依赖只用到了一个小函数", "detail": "可以考虑直接实现，减少依赖。"}]
+` + "```" + `
+
+没有问题时输出空数组 []。`
+
+// IsModFile 报告审查单元的 key 是否为依赖检查的单元，即某个模块的 go.mod。
+func IsModFile(key string) bool {
+	return path.Base(key) == "go.mod"
+}
+
+// DepsUnits 返回 go.mod 或 go.sum 在审查范围内有变化的模块对应的审查单元，key 为 go.mod 的路径。
+// 未开启 Options.Deps 或读取变更文件失败时返回空。
+func (r *Runner) DepsUnits() []Unit {
+	if r == nil || !r.opts.Deps {
+		return nil
+	}
+
+	var (
+		files []string
+		err   error
+	)
+	if base, head := r.Range(); head != "" {
+		files, err = gitops.GetRangeFiles(base, head)
+	} else {
+		files, err = gitops.GetStagedFiles()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var units []Unit
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if name := path.Base(f); name != "go.mod" && name != "go.sum" {
+			continue
+		}
+		mod := path.Join(path.Dir(f), "go.mod")
+		if !seen[mod] {
+			seen[mod] = true
+			units = append(units, Unit{Key: mod, Files: []string{mod}})
+		}
+	}
+	return units
+}
+
+// sumFile 返回与 go.mod 同目录的 go.sum 的路径。
+func sumFile(mod string) string {
+	return path.Join(path.Dir(mod), "go.sum")
+}
+
+// depsDiff 返回 mod 与同目录 go.sum 在审查范围内的 diff 拼接，用于判断依赖是否需要重新检查。
+func (r *Runner) depsDiff(mod string) (string, error) {
+	var diffs []string
+	for _, f := range []string{mod, sumFile(mod)} {
+		// 其中一个文件没有变化时 git diff 没有输出，忽略即可
+		if diff, err := r.fileDiff(f); err == nil {
+			diffs = append(diffs, strings.TrimRight(diff, "\n"))
+		}
+	}
+	if len(diffs) == 0 {
+		return "", fmt.Errorf("%s 与 go.sum 都没有 diff 输出", mod)
+	}
+	return strings.Join(diffs, "\n"), nil
+}
+
+// baseContent 返回 file 在审查范围起点的内容：Options.Base 中的，审查暂存区时为 HEAD 中的。
+// 文件在起点不存在（或仓库还没有提交）时返回空字符串。
+func (r *Runner) baseContent(file string) string {
+	base, head := r.Range()
+	if head == "" {
+		base = "HEAD"
+	}
+	content, _ := gitops.GetFileContentAt(base, file)
+	return content
+}
+
+// reviewDeps 检查 mod 所在模块的依赖变化：先比较起点与最新的 go.mod、go.sum（见 deps.Compare），
+// 再把检查结果连同 go.mod 的 diff 发送给 LLM 解释。检查结果放在报告开头，其中需要关注的部分同时作为问题列出。
+//
+// 检查本身不依赖 LLM：外发策略不允许发送、密钥拦截、预算用尽或请求失败时，仍返回包含检查结果与问题的 FileReview，
+// 报告中注明 LLM 解释未能生成的原因，同时返回该错误。
+func (r *Runner) reviewDeps(mod string, fresh bool) (*FileReview, error) {
+	if r == nil || r.provider == nil {
+		return nil, errors.New("LLM Provider 未初始化")
+	}
+
+	diff, err := r.depsDiff(mod)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 的 diff 失败：%w", mod, err)
+	}
+
+	content, _ := r.fileContent(mod)
+	sum, _ := r.fileContent(sumFile(mod))
+	before := deps.Files{Mod: r.baseContent(mod), Sum: r.baseContent(sumFile(mod))}
+	report := deps.Compare(before, deps.Files{Mod: content, Sum: sum}, r.opts.DepsOptions)
+	reportMarkdown := report.Markdown()
+
+	rev := &FileReview{File: mod, Diff: diff}
+	explainErr := r.explainDeps(rev, reportMarkdown, fresh)
+	if explainErr != nil {
+		rev.Markdown = fmt.Sprintf("_未能生成 LLM 解释：%v_", explainErr)
+	}
+	rev.Markdown = reportMarkdown + "\n" + rev.Markdown
+
+	// 校验和的问题位于 go.sum，按各自文件的内容计算指纹
+	findings := append(rev.Findings, depsFindings(mod, report)...)
+	sortFindings(findings)
+	rev.Findings = r.applyDecisionsByFile(findings, map[string]string{mod: content, sumFile(mod): sum})
+	if explainErr != nil {
+		return rev, explainErr
+	}
+
+	if r.opts.Recorder != nil {
+		// 历史记录只是附加功能，写入失败不影响本次审查结果。
+		_ = r.opts.Recorder.Record(rev)
+	}
+	return rev, nil
+}
+
+// explainDeps 把依赖检查结果 reportMarkdown 连同 go.mod 的 diff 发送给 LLM，
+// 把回复与其中的问题、用量等写入 rev。外发策略不允许发送 go.mod 时返回包装了 policy.ErrDenied 的错误。
+func (r *Runner) explainDeps(rev *FileReview, reportMarkdown string, fresh bool) error {
+	mod := rev.File
+	if err := r.opts.Policy.Check(r.Identity().Provider, mod); err != nil {
+		return err
+	}
+
+	// go.sum 的 diff 只有校验和，对解释没有帮助，不发送给 LLM
+	modDiff, _ := r.fileDiff(mod)
+	prompt := buildDepsPrompt(modDiff, reportMarkdown)
+
+	redactions, err := r.redactionReport(prompt, fmt.Sprintf("审查 %s 失败", mod))
+	if err != nil {
+		return err
+	}
+
+	key := ""
+	if r.opts.Cache != nil {
+		id := r.Identity()
+		key = cache.Key(id.Provider, id.Model, PromptVersion, buildDepsPrompt(normalizeDiff(modDiff), reportMarkdown))
+	}
+	req := ai.Request{
+		Messages: []ai.Message{{Role: ai.RoleUser, Content: prompt}},
+		Files:    []string{mod},
+	}
+	reply, cached, used, err := r.sendCached(req, key, fresh)
+	rev.Usage = used
+	if err != nil {
+		return fmt.Errorf("审查 %s 失败：%w", mod, err)
+	}
+
+	rev.Markdown, rev.Findings = reply, nil
+	if !r.opts.DryRun {
+		rev.Markdown, rev.Findings = extractFindings(reply, mod, nil)
+	}
+	rev.Cached = cached
+	rev.Redactions = redactions
+	return nil
+}
+
+// buildDepsPrompt 构造依赖检查的提示词：系统说明、go.mod 的 diff 与依赖检查结果。
+func buildDepsPrompt(diff, report string) string {
+	var b strings.Builder
+	b.WriteString(depsSystemPrompt)
+	if diff = strings.TrimSpace(diff); diff != "" {
+		fmt.Fprintf(&b, "\n\ngo.mod 的 diff：\n\n```diff\n%s\n```", diff)
+	} else {
+		b.WriteString("\n\ngo.mod 没有变化，只有 go.sum 发生了变化。")
+	}
+	fmt.Fprintf(&b, "\n\n依赖检查结果：\n\n%s", report)
+	return b.String()
+}
+
+// depsFindings 把依赖检查结果中需要关注的部分转换为问题：大版本升级、降级、新增的 replace、已知漏洞、
+// 许可证变化与 go.sum 校验和变化。普通的新增与升级只在报告中列出。
+func depsFindings(mod string, report *deps.Report) []Finding {
+	var findings []Finding
+	add := func(sev Severity, file string, line int, title, detail string) {
+		findings = append(findings, Finding{Source: SourceDeps, Severity: sev, File: file, Line: line, Title: title, Detail: detail})
+	}
+
+	lines := make(map[string]int, len(report.Changes))
+	for _, c := range report.Changes {
+		lines[c.Path] = c.Line
+		switch {
+		case c.Major():
+			from := c.Old
+			if c.OldPath != "" {
+				from = c.OldPath + "@" + c.Old
+			}
+			add(SeverityMedium, mod, c.Line, fmt.Sprintf("大版本升级：%s %s → %s", c.Path, from, c.New),
+				"跨越主版本的升级通常包含不兼容的 API 变化，请确认调用方已经全部适配。")
+		case c.Kind() == "降级":
+			add(SeverityLow, mod, c.Line, fmt.Sprintf("依赖降级：%s %s → %s", c.Path, c.Old, c.New),
+				"降级可能重新引入已经修复的缺陷或漏洞，请确认这是有意为之。")
+		}
+	}
+
+	for _, rp := range report.Replaces {
+		if rp.Local() {
+			add(SeverityHigh, mod, rp.Line, fmt.Sprintf("replace 指向本地路径：%s", rp),
+				"本地路径在其他机器、CI 以及依赖本模块的下游项目中都不存在，通常只应在本地调试时使用。")
+			continue
+		}
+		add(SeverityMedium, mod, rp.Line, fmt.Sprintf("新增或修改了 replace 指令：%s", rp),
+			"replace 只对主模块生效，下游项目不会继承；请确认这是临时措施，并说明原因与移除计划。")
+	}
+
+	for _, v := range report.Vulns {
+		detail := v.Summary
+		if v.Fixed != "" {
+			detail += fmt.Sprintf("。请升级到 %s 或更高版本。", v.Fixed)
+		} else {
+			detail += "。该漏洞尚无修复版本，请评估是否确实调用了受影响的代码。"
+		}
+		add(SeverityHigh, mod, lines[v.Module], fmt.Sprintf("%s@%s 存在已知漏洞 %s", v.Module, v.Version, v.ID), detail)
+	}
+
+	for _, l := range report.Licenses {
+		switch {
+		case l.Old == "" && deps.Copyleft(l.New):
+			add(SeverityMedium, mod, l.Line, fmt.Sprintf("新增依赖 %s 使用 %s 许可证", l.Module, l.New),
+				"传染性许可证可能对本项目的分发方式提出要求，请确认符合团队的许可证政策。")
+		case l.Old != "":
+			sev := SeverityMedium
+			if deps.Copyleft(l.New) && !deps.Copyleft(l.Old) {
+				sev = SeverityHigh
+			}
+			add(sev, mod, l.Line, fmt.Sprintf("%s 的许可证由 %s 变为 %s", l.Module, l.Old, l.New),
+				"许可证变化可能影响本项目的使用与分发方式，请在合入前确认。")
+		}
+	}
+
+	for _, s := range report.Sums {
+		add(SeverityHigh, sumFile(mod), 0, fmt.Sprintf("%s %s 的校验和发生了变化", s.Module, s.Version),
+			"同一版本的校验和不应改变，可能是依赖被篡改或代理返回了错误的内容，请用 go mod verify 检查。")
+	}
+	return findings
+}
@@ -0,0 +1,268 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/analysis"
+)
+
+// Severity 表示一条问题的严重程度。
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+	SeverityInfo   Severity = "info"
+)
+
+// SourceLLM 是由 LLM 给出的问题的来源名称；静态分析工具的问题使用工具名（如 "go vet"）。
+const SourceLLM = "llm"
+
+// SourceOverall 是整体审查（见 Runner.ReviewOverall）中 LLM 给出的跨文件问题的来源名称。
+const SourceOverall = "overall"
+
+// Rank 返回便于比较的严重程度数值，越大越严重。未知取值视为 info。
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityHigh:
+		return 3
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity 宽松地解析严重程度字符串，兼容常见的中英文写法。
+func ParseSeverity(s string) Severity {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "high", "critical", "blocker", "error", "高", "严重":
+		return SeverityHigh
+	case "medium", "moderate", "warning", "中":
+		return SeverityMedium
+	case "low", "minor", "低":
+		return SeverityLow
+	default:
+		return SeverityInfo
+	}
+}
+
+// Finding 是一条结构化的审查问题，可能来自 LLM，也可能来自静态分析工具。
+//
+// Line 为新文件一侧的行号，未知时为 0。
+type Finding struct {
+	Source   string
+	Severity Severity
+	File     string
+	Line     int
+	Title    string
+	Detail   string
+
+	// Patch 是修复模式下 LLM 针对该问题建议的 unified diff，可能为空。
+	Patch string
+	// PatchErr 记录 git apply --check 的校验失败原因：审查时针对暂存区校验，在 TUI 中应用了其他补丁后
+	// 针对其应用的目标重新校验。Patch 非空且 PatchErr 为空表示补丁可以干净地应用。
+	PatchErr string
+	// Verification 是补丁在临时 worktree 中的编译 / 测试验证结果，未验证时为 nil。
+	Verification *Verification
+
+	// Fingerprint 是跨运行稳定的问题指纹，见 Fingerprint。
+	Fingerprint string
+	// Decision 是用户此前对该问题做出的决定，没有时为 nil。
+	Decision *Decision
+}
+
+// HasValidPatch 报告该问题是否带有通过校验、可以直接应用的补丁。
+func (f Finding) HasValidPatch() bool {
+	return f.Patch != "" && f.PatchErr == ""
+}
+
+// findingsBlockRe 匹配 LLM 回复末尾的 ```findings 代码块。
+var findingsBlockRe = regexp.MustCompile("(?s)```findings\\s*\\n(.*?)```")
+
+// llmFinding 是提示词中约定的 JSON 结构。
+type llmFinding struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Title    string `json:"title"`
+	Detail   string `json:"detail"`
+	Patch    string `json:"patch"`
+}
+
+// extractFindings 从 LLM 回复中取出结构化问题列表，并返回去掉该代码块后的 Markdown。
+//
+// 模型不一定严格遵守格式：代码块缺失或 JSON 无法解析时，原样返回回复且不报错，
+// 此时该文件只会展示 Markdown 审查内容。
+//
+// files 非空时（整体审查或分组审查）问题所在的文件取自各条目的 file 字段，并对应到 files 中的路径；
+// 无法对应时问题的文件记为 file。
+func extractFindings(reply, file string, files []string) (string, []Finding) {
+	loc := findingsBlockRe.FindStringSubmatchIndex(reply)
+	if loc == nil {
+		return reply, nil
+	}
+
+	var raw []llmFinding
+	if err := json.Unmarshal([]byte(reply[loc[2]:loc[3]]), &raw); err != nil {
+		return reply, nil
+	}
+
+	findings := make([]Finding, 0, len(raw))
+	for _, f := range raw {
+		if strings.TrimSpace(f.Title) == "" {
+			continue
+		}
+		name := file
+		if resolved := resolveFile(f.File, files); resolved != "" {
+			name = resolved
+		}
+		findings = append(findings, Finding{
+			Source:   SourceLLM,
+			Severity: ParseSeverity(f.Severity),
+			File:     name,
+			Line:     f.Line,
+			Title:    strings.TrimSpace(f.Title),
+			Detail:   strings.TrimSpace(f.Detail),
+			Patch:    normalizePatch(f.Patch, name),
+		})
+	}
+
+	markdown := strings.TrimSpace(reply[:loc[0]] + reply[loc[1]:])
+	return markdown, findings
+}
+
+// resolveFile 把模型给出的文件路径对应到 files 中的文件，兼容只写文件名、带 a/ b/ 或 ./ 前缀的写法。
+// 无法对应时返回空字符串。
+func resolveFile(name string, files []string) string {
+	name = strings.TrimSpace(name)
+	for _, prefix := range []string{"a/", "b/", "./"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	if name == "" {
+		return ""
+	}
+
+	for _, f := range files {
+		if f == name {
+			return f
+		}
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f, "/"+name) {
+			return f
+		}
+	}
+	return ""
+}
+
+// normalizePatch 整理 LLM 给出的补丁：去掉可能包裹的代码块标记，
+// 缺少文件头时按 a/ b/ 前缀补齐，并保证以换行结尾（git apply 要求）。
+func normalizePatch(patch, file string) string {
+	patch = strings.TrimSpace(patch)
+	if patch == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(patch, "```") {
+		if i := strings.IndexByte(patch, '\n'); i >= 0 {
+			patch = patch[i+1:]
+		}
+		patch = strings.TrimSuffix(strings.TrimSpace(patch), "```")
+	}
+
+	if !strings.Contains(patch, "\n+++ ") && !strings.HasPrefix(patch, "+++ ") {
+		patch = fmt.Sprintf("--- a/%s\n+++ b/%s\n%s", file, file, strings.TrimPrefix(patch, "\n"))
+	}
+
+	return strings.TrimRight(patch, "\n") + "\n"
+}
+
+// findingsFromDiagnostics 把静态分析诊断转换为 Finding。
+func findingsFromDiagnostics(diags []analysis.Diagnostic) []Finding {
+	findings := make([]Finding, 0, len(diags))
+	for _, d := range diags {
+		f := Finding{
+			Source:   d.Tool,
+			Severity: SeverityMedium,
+			File:     d.File,
+			Line:     d.Line,
+			Title:    d.Message,
+		}
+		if d.Tool == SourceAPI {
+			f.Severity = SeverityHigh
+			f.Detail = "这是导出 API 的不兼容变化，依赖该包的下游代码将无法编译；如确有必要，请在发布说明中注明并考虑升级主版本。"
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// sortFindings 按严重程度降序、行号升序排列。
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity.Rank() != findings[j].Severity.Rank() {
+			return findings[i].Severity.Rank() > findings[j].Severity.Rank()
+		}
+		return findings[i].Line < findings[j].Line
+	})
+}
+
+// FindingsMarkdown 把 file 的问题列表渲染为一个 Markdown 小节，便于在 TUI 中与审查报告一起展示。
+// selected 为当前选中问题的下标，会以 "▶" 标出；传入 -1 表示不标记。列表为空时返回空字符串。
+//
+// file 为审查结果的 key：整体审查或分组审查中问题分属不同文件，所在文件与 file 不同时会在行号前标出。
+func FindingsMarkdown(file string, findings []Finding, selected int) string {
+	if len(findings) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## 问题列表\n\n")
+	for i, f := range findings {
+		marker := ""
+		if i == selected {
+			marker = "▶ "
+		}
+		loc := ""
+		switch {
+		case f.File != file && f.File != "" && f.Line > 0:
+			loc = fmt.Sprintf(" %s:%d", f.File, f.Line)
+		case f.File != file && f.File != "":
+			loc = " " + f.File
+		case f.Line > 0:
+			loc = fmt.Sprintf(" L%d", f.Line)
+		}
+		fmt.Fprintf(&b, "- %s**[%s]** `%s`%s %s", marker, f.Severity, f.Source, loc, f.Title)
+		if d := f.Decision; d != nil {
+			if d.Reason != "" {
+				fmt.Fprintf(&b, " **（%s：%s）**", d.Status.Label(), d.Reason)
+			} else {
+				fmt.Fprintf(&b, " **（%s）**", d.Status.Label())
+			}
+		}
+		if f.Detail != "" {
+			fmt.Fprintf(&b, " —— %s", f.Detail)
+		}
+		switch {
+		case f.HasValidPatch() && f.Verification != nil:
+			fmt.Fprintf(&b, " _（附可应用补丁，%s）_", f.Verification.Summary())
+		case f.HasValidPatch():
+			b.WriteString(" _（附可应用补丁）_")
+		case f.Patch != "":
+			b.WriteString(" _（补丁无法应用）_")
+		case f.Verification != nil:
+			fmt.Fprintf(&b, " _（补丁%s，已丢弃）_", f.Verification.Summary())
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
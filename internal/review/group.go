@@ -0,0 +1,368 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/analysis"
+	"github.com/GuLuGuLuGit/review-go/internal/cache"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// 审查单元的划分方式，见 Options.Group。
+const (
+	GroupFile    = "file"
+	GroupPackage = "package"
+	GroupModule  = "module"
+)
+
+// groupInstructions 在分组审查时追加到系统说明之后，%s 为 "包" 或 "模块"，%s 为其目录。
+const groupInstructions = `
+
+分组审查：下面是%s %s 中有变更的全部文件，请把它们作为一个整体审查：除了每个文件内部的问题，
+也要关注这些文件之间的调用、接口、类型与约定是否一致。findings 中的每一条都需要额外给出 file 字段，
+取值为问题所在文件的路径（与下面列出的路径一致），line 为该文件新版本中的行号。`
+
+// Unit 是一次审查的单位：按文件审查时是单个文件，按包或模块分组时是其中有变更的全部文件。
+//
+// - Key: 在文件列表与审查结果中使用的 key。按文件审查时为文件路径，分组时为以 "/" 结尾的目录，例如 "internal/ui/"
+// - Files: 单元中有变更的文件
+type Unit struct {
+	Key   string
+	Files []string
+}
+
+// Grouped 报告是否按包或模块分组审查。
+func (r *Runner) Grouped() bool {
+	return r != nil && (r.opts.Group == GroupPackage || r.opts.Group == GroupModule)
+}
+
+// Units 把 files 划分为审查单元。单元按其第一个文件在 files 中的位置排列，
+// 因此 files 已按风险评分排序时，包含高风险文件的单元排在前面。
+//
+// 按模块分组时，文件归入向上最近的 go.mod 所在目录；找不到 go.mod 时归入仓库根目录。
+func (r *Runner) Units(files []string) []Unit {
+	units := make([]Unit, 0, len(files))
+	if !r.Grouped() {
+		for _, f := range files {
+			units = append(units, Unit{Key: f, Files: []string{f}})
+		}
+		return units
+	}
+
+	root := ""
+	if r.opts.Group == GroupModule {
+		root, _ = gitops.GetRepoRoot()
+	}
+
+	index := make(map[string]int)
+	for _, f := range files {
+		key := unitKey(root, f)
+		if i, ok := index[key]; ok {
+			units[i].Files = append(units[i].Files, f)
+			continue
+		}
+		index[key] = len(units)
+		units = append(units, Unit{Key: key, Files: []string{f}})
+	}
+	return units
+}
+
+// unitKey 返回 file 所属单元的 key：root 为空时为文件所在目录（包），否则为其所属模块相对 root 的目录。
+func unitKey(root, file string) string {
+	dir := path.Dir(file)
+	if root != "" {
+		dir = "."
+		if mod := findModuleDir(root, filepath.Join(root, filepath.FromSlash(path.Dir(file)))); mod != "" {
+			if rel, err := filepath.Rel(root, mod); err == nil {
+				dir = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return dir + "/"
+}
+
+// UnitDiff 返回单元在审查范围内的 diff：其中允许发送给当前提供商的文件的 diff 拼接。
+// 与 ReviewUnit 结果中的 Diff 一致，用于判断单元是否需要重新审查。
+func (r *Runner) UnitDiff(u Unit) (string, error) {
+	switch {
+	case IsModFile(u.Key):
+		return r.depsDiff(u.Key)
+	case !r.Grouped():
+		return r.fileDiff(u.Key)
+	}
+
+	files, _ := r.allowedFiles(u.Files)
+	diffs := make([]string, 0, len(files))
+	for _, f := range files {
+		diff, err := r.fileDiff(f)
+		if err != nil {
+			return "", fmt.Errorf("获取文件 %s 的 diff 失败：%w", f, err)
+		}
+		diffs = append(diffs, strings.TrimRight(diff, "\n"))
+	}
+	return strings.Join(diffs, "\n"), nil
+}
+
+// ReviewUnit 审查一个单元：按文件审查时等同于 ReviewFile（fresh 时为 ReviewFileFresh），
+// 分组时把单元中的文件作为一个整体发送给 LLM，go.mod（见 DepsUnits）检查依赖的变化。
+// diags 为 Analyze 的结果，可以包含其他文件的诊断。
+//
+// 返回错误时 FileReview 不一定为 nil：LLM 请求失败（包括外发策略不允许发送、预算用尽）时，
+// 不依赖 LLM 的结果（依赖检查，以及静态分析与导出 API 检查发现的问题）仍随错误一起返回，调用方应当展示它并同时报告错误。
+func (r *Runner) ReviewUnit(u Unit, diags map[string][]analysis.Diagnostic, fresh bool) (*FileReview, error) {
+	switch {
+	case IsModFile(u.Key):
+		return r.reviewDeps(u.Key, fresh)
+	case !r.Grouped():
+		return r.reviewFile(u.Key, diags[u.Key], fresh)
+	}
+	return r.reviewGroup(u, diags, fresh)
+}
+
+// allowedFiles 按外发策略过滤 files，返回允许发送的文件；有文件被拒绝时 denied 为其中一个拒绝原因（包装了 policy.ErrDenied）。
+func (r *Runner) allowedFiles(files []string) (allowed []string, denied error) {
+	provider := r.Identity().Provider
+	for _, f := range files {
+		if err := r.opts.Policy.Check(provider, f); err != nil {
+			denied = err
+			continue
+		}
+		allowed = append(allowed, f)
+	}
+	return allowed, denied
+}
+
+// reviewGroup 把单元中的文件作为一个整体审查。外发策略不允许发送的文件不包含在请求中，并在报告开头列出；
+// 全部文件都不允许发送时返回包装了 policy.ErrDenied 的错误。
+func (r *Runner) reviewGroup(u Unit, diags map[string][]analysis.Diagnostic, fresh bool) (*FileReview, error) {
+	if r == nil || r.provider == nil {
+		return nil, errors.New("LLM Provider 未初始化")
+	}
+
+	// 诊断不依赖 LLM，包括按外发策略不发送的文件在内，单元中所有文件的诊断都要报告
+	var groupDiags []analysis.Diagnostic
+	for _, f := range u.Files {
+		groupDiags = append(groupDiags, diags[f]...)
+	}
+
+	files, deniedErr := r.allowedFiles(u.Files)
+	if len(files) == 0 {
+		return r.diagnosticsReview(u.Key, "", groupDiags, nil, llmFailedNote(deniedErr)), deniedErr
+	}
+
+	in := groupPromptInput{Key: u.Key, Fix: r.opts.Fix, Module: r.opts.Group == GroupModule}
+	contents := make(map[string]string, len(files))
+	diffs := make([]string, 0, len(files))
+	for _, f := range files {
+		diff, err := r.fileDiff(f)
+		if err != nil {
+			return nil, fmt.Errorf("获取文件 %s 的 diff 失败：%w", f, err)
+		}
+		content, _ := r.fileContent(f)
+		contents[f] = content
+
+		in.Files = append(in.Files, groupFile{Name: f, Diff: diff, Diagnostics: diags[f]})
+		diffs = append(diffs, strings.TrimRight(diff, "\n"))
+	}
+	unitDiff := strings.Join(diffs, "\n")
+	omitted := in.fit(contents)
+	prompt := buildGroupPrompt(in)
+
+	redactions, err := r.redactionReport(prompt, fmt.Sprintf("审查 %s 失败", u.Key))
+	if err != nil {
+		return r.diagnosticsReview(u.Key, unitDiff, groupDiags, contents, llmFailedNote(err)), err
+	}
+
+	key := ""
+	if r.opts.Cache != nil {
+		id := r.Identity()
+		key = cache.Key(id.Provider, id.Model, PromptVersion, buildGroupPrompt(in.normalized()))
+	}
+	req := ai.Request{
+		Messages: []ai.Message{{Role: ai.RoleUser, Content: prompt}},
+		Files:    files,
+	}
+	reply, cached, used, err := r.sendCached(req, key, fresh)
+	if err != nil {
+		err = fmt.Errorf("审查 %s 失败：%w", u.Key, err)
+		return r.diagnosticsReview(u.Key, unitDiff, groupDiags, contents, llmFailedNote(err)), err
+	}
+
+	markdown, findings := reply, []Finding(nil)
+	if !r.opts.DryRun {
+		markdown, findings = extractFindings(reply, u.Key, files)
+	}
+	for i := range findings {
+		if findings[i].Patch == "" {
+			continue
+		}
+		if err := gitops.CheckPatch(findings[i].Patch, true); err != nil {
+			findings[i].PatchErr = err.Error()
+		}
+	}
+	if r.opts.VerifyFixes {
+		r.verifyFixes(findings)
+	}
+	findings = append(findings, findingsFromDiagnostics(groupDiags)...)
+	sortFindings(findings)
+	findings = r.applyDecisionsByFile(findings, contents)
+
+	if len(omitted) > 0 {
+		markdown = fmt.Sprintf("_diff 过长，只向模型发送了文件名：%s_\n\n%s", strings.Join(omitted, "、"), markdown)
+	}
+	if deniedErr != nil {
+		var denied []string
+		for _, f := range u.Files {
+			if !slices.Contains(files, f) {
+				denied = append(denied, f)
+			}
+		}
+		markdown = fmt.Sprintf("_按外发策略未发送：%s_\n\n%s", strings.Join(denied, "、"), markdown)
+	}
+
+	rev := &FileReview{
+		File:       u.Key,
+		Diff:       unitDiff,
+		Markdown:   markdown,
+		Findings:   findings,
+		Cached:     cached,
+		Usage:      used,
+		Redactions: redactions,
+		Files:      files,
+	}
+	if r.opts.Recorder != nil {
+		// 历史记录只是附加功能，写入失败不影响本次审查结果。
+		_ = r.opts.Recorder.Record(rev)
+	}
+	return rev, nil
+}
+
+// groupFile 是分组审查提示词中的单个文件。Content 仅修复模式下、总量未超出 maxChangeDiffBytes 时提供；
+// Omitted 为 true 时 diff 过长，提示词中只列出文件名与诊断。
+type groupFile struct {
+	Name        string
+	Diff        string
+	Content     string
+	Omitted     bool
+	Diagnostics []analysis.Diagnostic
+}
+
+// groupPromptInput 汇总构造分组审查提示词所需的信息，含义与 promptInput 相同，只是包含多个文件。
+type groupPromptInput struct {
+	Key    string
+	Module bool
+	Fix    bool
+	Files  []groupFile
+}
+
+// fit 按与整体审查相同的上限 maxChangeDiffBytes 决定提示词中包含哪些内容，返回只列出文件名的文件。
+//
+// 按顺序放入各文件的 diff，放不下的文件标记为 Omitted；第一个文件的 diff 总是发送，与逐个文件审查时一致。
+// 修复模式下再用剩余的空间按顺序附上 contents 中各文件的完整内容，放不下的文件只提供 diff。
+func (in *groupPromptInput) fit(contents map[string]string) []string {
+	var omitted []string
+	size := 0
+	for i := range in.Files {
+		f := &in.Files[i]
+		if i > 0 && size+len(f.Diff) > maxChangeDiffBytes {
+			f.Omitted = true
+			omitted = append(omitted, f.Name)
+			continue
+		}
+		size += len(f.Diff)
+	}
+	if !in.Fix {
+		return omitted
+	}
+	for i := range in.Files {
+		f := &in.Files[i]
+		content := contents[f.Name]
+		if f.Omitted || size+len(content) > maxChangeDiffBytes {
+			continue
+		}
+		f.Content = content
+		size += len(content)
+	}
+	return omitted
+}
+
+// normalized 返回用规范化后的 diff 构造缓存 key 时使用的副本，见 normalizeDiff。
+func (in groupPromptInput) normalized() groupPromptInput {
+	out := in
+	out.Files = make([]groupFile, len(in.Files))
+	for i, f := range in.Files {
+		f.Diff = normalizeDiff(f.Diff)
+		out.Files[i] = f
+	}
+	return out
+}
+
+// buildGroupPrompt 构造分组审查的提示词：与 buildReviewPrompt 使用相同的系统说明，
+// 再附上分组说明、全部文件的 diff、修复模式下各文件的完整内容与静态分析诊断。
+func buildGroupPrompt(in groupPromptInput) string {
+	kind := "包"
+	if in.Module {
+		kind = "模块"
+	}
+
+	var b strings.Builder
+	b.WriteString(systemPrompt)
+	if in.Fix {
+		b.WriteString(fixInstructions)
+	}
+	fmt.Fprintf(&b, groupInstructions, kind, in.Key)
+
+	b.WriteString("\n\n请审查以下 Git diff")
+	if in.Fix {
+		b.WriteString("，并按照上述要求返回 Markdown 格式的审查报告，可修复的问题请在 findings 中附带 patch：\n\n")
+	} else {
+		b.WriteString("（只读即可，不需要给出可直接应用的 patch），并按照上述要求返回 Markdown 格式的审查报告：\n\n")
+	}
+	b.WriteString("```diff\n")
+	var omitted []string
+	for _, f := range in.Files {
+		if f.Omitted {
+			omitted = append(omitted, f.Name)
+			continue
+		}
+		b.WriteString(strings.TrimRight(f.Diff, "\n") + "\n")
+	}
+	b.WriteString("```")
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "\n\n以下文件的 diff 过长，已省略，请根据文件名与其他文件推断：%s", strings.Join(omitted, "、"))
+	}
+
+	for _, f := range in.Files {
+		if f.Content != "" {
+			fmt.Fprintf(&b, "\n\n文件 %s 在暂存区中的完整内容如下，补丁的上下文行必须与之一致：\n\n```go\n%s\n```", f.Name, f.Content)
+		}
+	}
+
+	var diags strings.Builder
+	api := false
+	for _, f := range in.Files {
+		for _, d := range f.Diagnostics {
+			if d.Line > 0 {
+				fmt.Fprintf(&diags, "- [%s] %s 第 %d 行：%s\n", d.Tool, f.Name, d.Line, d.Message)
+			} else {
+				fmt.Fprintf(&diags, "- [%s] %s：%s\n", d.Tool, f.Name, d.Message)
+			}
+		}
+		api = api || hasAPIDiagnostics(f.Diagnostics)
+	}
+	if diags.Len() > 0 {
+		b.WriteString("\n\n以下是静态分析工具在本次变更行上报告的问题。请在审查中逐条解释其含义与影响，判断是否为误报，并结合其他问题排定优先级：\n\n")
+		b.WriteString(diags.String())
+		if api {
+			b.WriteString(apiNote)
+		}
+	}
+
+	return b.String()
+}
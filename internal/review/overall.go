@@ -0,0 +1,199 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/cache"
+)
+
+// OverallFile 是整体审查结果在文件列表中使用的 key。ChangedFiles 只返回 .go 文件，因此不会与真实文件冲突。
+const OverallFile = "(overall)"
+
+// maxSummaryRunes 是整体审查中每个文件审查摘要的长度上限（字符数）。
+const maxSummaryRunes = 600
+
+const overallSystemPrompt = `你是一名资深 Golang 专家。下面这次变更中的每个文件都已经单独审查过，
+现在请把全部变更作为一个整体审查，只关注逐个文件审查时看不到的跨文件问题，例如：
+
+- 修改了函数签名、接口、结构体字段或导出标识符，而其他文件中的调用方或实现没有同步更新
+- 同一个常量、配置项、错误值或约定在不同文件中的用法不一致
+- 新增的功能缺少配套的注册、配置、文档或测试
+- 锁、goroutine 或资源的获取与释放分散在多个文件中，彼此的约定被破坏
+- 单看每个文件都没有问题，组合起来却改变了行为或破坏了兼容性
+
+不要重复单个文件内部的问题。请先用单独一行给出整体风险评级，格式为 "整体风险：high"（取 high / medium / low 之一），
+然后以 Markdown 格式输出：
+
+## 变更概述
+- 用几句话概括这次变更整体做了什么。
+
+## 跨文件问题
+- 按严重程度列出跨文件的问题，注明涉及的文件与行号；没有时写“未发现”。
+
+## 风险说明
+- 说明给出该风险评级的理由，以及合入前最值得人工确认的地方。
+
+在 Markdown 报告之后，请追加一个语言标记为 findings 的代码块，用 JSON 数组列出“跨文件问题”中的每一条，
+字段为 severity（high / medium / low / info）、file（问题所在的文件路径）、line（该文件新版本中的行号，未知时为 0）、
+title（一句话概括）、detail（简要说明），例如：
+
+` + "```findings" + `
+[{"severity": "high", "file": "internal/store/store.go", "line": 27, "title": "Open 新增了 ctx 参数，cmd/serve.go 中的调用未更新", "detail": "调用方仍按旧签名调用，无法通过编译。"}]
+` + "```" + `
+
+没有问题时输出空数组 []。`
+
+// overallRiskRe 匹配回复中 "整体风险：high" 这一行，兼容模型加上的标题、列表或加粗标记。
+var overallRiskRe = regexp.MustCompile(`(?mi)^[#>*\-\s]*整体风险\s*[:：]\s*\**\s*(high|medium|low|高|中|低)[^\n]*$`)
+
+// WantsOverall 报告对 files（ChangedFiles 的结果）是否需要整体审查：开启了 Options.Overall 且变更涉及多个文件。
+func (r *Runner) WantsOverall(files []string) bool {
+	return r != nil && r.opts.Overall && len(files) > 1
+}
+
+// ReviewOverall 在逐个文件审查之后，把暂存区（或 Options.Base 与 Head 之间）的全部变更作为一个整体发送给 LLM，
+// 查找跨文件的问题并给出整体风险评级。
+//
+// diff 总量超过 maxChangeDiffBytes 时，放不下的文件改用 reviews 中该文件审查报告的摘要。
+// 返回结果的 File 为 OverallFile，Files 为包含在请求中的文件，Risk 为整体风险评级。
+// 开启缓存时，相同的请求直接复用缓存中的回复；fresh 为 true 时跳过读取缓存。
+func (r *Runner) ReviewOverall(reviews map[string]*FileReview, fresh bool) (*FileReview, error) {
+	if r == nil || r.provider == nil {
+		return nil, errors.New("LLM Provider 未初始化")
+	}
+
+	cs, err := r.collectChanges()
+	if err != nil {
+		return nil, err
+	}
+	prompt := buildOverallPrompt(cs, reviews)
+
+	redactions, err := r.redactionReport(prompt, "整体审查失败")
+	if err != nil {
+		return nil, err
+	}
+
+	key := ""
+	if r.opts.Cache != nil {
+		id := r.Identity()
+		key = cache.Key(id.Provider, id.Model, PromptVersion, prompt)
+	}
+	req := ai.Request{
+		Messages: []ai.Message{{Role: ai.RoleUser, Content: prompt}},
+		Files:    cs.files,
+	}
+	reply, cached, used, err := r.sendCached(req, key, fresh)
+	if err != nil {
+		return nil, fmt.Errorf("整体审查失败：%w", err)
+	}
+
+	rev := &FileReview{
+		File:       OverallFile,
+		Files:      cs.files,
+		Diff:       cs.diff,
+		Markdown:   reply,
+		Cached:     cached,
+		Usage:      used,
+		Redactions: redactions,
+	}
+	if r.opts.DryRun {
+		return rev, nil
+	}
+
+	markdown, findings := extractFindings(reply, OverallFile, cs.files)
+	rev.Markdown, rev.Risk = extractRisk(markdown)
+	for i := range findings {
+		findings[i].Source = SourceOverall
+	}
+	sortFindings(findings)
+	rev.Findings = r.applyDecisionsByFile(findings, nil)
+	return rev, nil
+}
+
+// buildOverallPrompt 构造整体审查的提示词：全部变更的 diff，放不下的文件附上逐个文件审查的摘要。
+func buildOverallPrompt(cs *changeSet, reviews map[string]*FileReview) string {
+	var b strings.Builder
+	b.WriteString(overallSystemPrompt)
+	b.WriteString("\n\n变更的文件：\n")
+	for _, f := range cs.files {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	if cs.diff != "" {
+		fmt.Fprintf(&b, "\n```diff\n%s\n```\n", cs.diff)
+	}
+
+	if len(cs.omitted) > 0 {
+		b.WriteString("\n以下文件的 diff 过长，改为提供逐个文件（或按包分组）审查时得到的摘要：\n")
+		summarized := make(map[*FileReview]string)
+		for _, f := range cs.omitted {
+			fmt.Fprintf(&b, "\n### %s\n\n", f)
+			rev := reviewCovering(reviews, f)
+			switch {
+			case rev == nil:
+				b.WriteString("（没有该文件的审查结果，请根据文件名推断）\n")
+			case summarized[rev] != "":
+				fmt.Fprintf(&b, "（与 %s 一起审查，见上面的摘要）\n", summarized[rev])
+			default:
+				summarized[rev] = f
+				b.WriteString(reviewSummary(rev) + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// reviewCovering 返回 reviews 中包含 file 的审查结果：按文件审查时为该文件的结果，分组审查时为其所在包（模块）的结果。
+func reviewCovering(reviews map[string]*FileReview, file string) *FileReview {
+	if rev := reviews[file]; rev != nil {
+		return rev
+	}
+	for _, rev := range reviews {
+		for _, f := range rev.Files {
+			if f == file {
+				return rev
+			}
+		}
+	}
+	return nil
+}
+
+// reviewSummary 从单个文件的审查报告中取出“总体评价”一节与问题标题，作为整体审查中该文件的摘要。
+// 报告中没有“总体评价”时截取报告开头。
+func reviewSummary(rev *FileReview) string {
+	md := strings.TrimSpace(rev.Markdown)
+	summary := md
+	if i := strings.Index(md, "## 总体评价"); i >= 0 {
+		summary = strings.TrimSpace(md[i+len("## 总体评价"):])
+		if j := strings.Index(summary, "\n## "); j >= 0 {
+			summary = strings.TrimSpace(summary[:j])
+		}
+	}
+	if utf8.RuneCountInString(summary) > maxSummaryRunes {
+		summary = string([]rune(summary)[:maxSummaryRunes]) + "…"
+	}
+
+	var b strings.Builder
+	b.WriteString(summary)
+	for _, f := range rev.Findings {
+		fmt.Fprintf(&b, "\n- [%s] %s:%d %s", f.Severity, f.File, f.Line, f.Title)
+	}
+	return b.String()
+}
+
+// extractRisk 从整体审查的回复中取出整体风险评级，并把该行统一改写为报告开头的加粗文字。
+// 找不到评级时原样返回回复，风险为空。
+func extractRisk(markdown string) (string, Severity) {
+	m := overallRiskRe.FindStringSubmatchIndex(markdown)
+	if m == nil {
+		return markdown, ""
+	}
+
+	risk := ParseSeverity(markdown[m[2]:m[3]])
+	rest := strings.TrimSpace(markdown[:m[0]] + markdown[m[1]:])
+	return fmt.Sprintf("**整体风险：%s**\n\n%s", risk, rest), risk
+}
@@ -0,0 +1,122 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// maxPRCommits 是生成 PR 描述时发送的提交记录条数上限。
+const maxPRCommits = 200
+
+// DefaultPRTemplate 是没有配置模板时使用的 PR 描述模板。注释说明了每一部分应当写什么，生成时会被替换为实际内容。
+const DefaultPRTemplate = `# <title>
+
+## Motivation
+
+<!-- Why is this change needed? What problem does it solve? -->
+
+## Changes
+
+<!-- A bullet list of the main changes. -->
+
+## Risks
+
+<!-- What could break: behaviour changes, compatibility, performance, security, migrations. Write "None" if there are none. -->
+
+## Testing
+
+<!-- A checklist of how to verify this change, as "- [ ]" items. -->
+`
+
+const prSystemPrompt = `你是一名资深工程师，请根据下面这个分支的提交记录与 diff 撰写一份 PR 描述。`
+
+// PRDescription 根据 Options.Base 与 Head 之间的提交记录与全部变更生成 PR 描述，
+// 结构取自 Options.PR 中配置的 Markdown 模板（未配置时为 DefaultPRTemplate）。
+func (r *Runner) PRDescription() (*Draft, error) {
+	if r == nil || r.provider == nil {
+		return nil, errors.New("LLM Provider 未初始化")
+	}
+	base, head := r.Range()
+	if head == "" {
+		return nil, errors.New("生成 PR 描述需要指定提交范围")
+	}
+
+	tmpl, err := r.prTemplate()
+	if err != nil {
+		return nil, err
+	}
+	log, err := gitops.GetRangeLog(base, head, maxPRCommits)
+	if err != nil {
+		return nil, fmt.Errorf("读取提交记录失败：%w", err)
+	}
+	cs, err := r.collectChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	draft, err := r.sendChanges(cs, r.buildPRPrompt(tmpl, log, cs))
+	if err != nil {
+		return nil, fmt.Errorf("生成 PR 描述失败：%w", err)
+	}
+
+	if !r.opts.DryRun {
+		draft.Message = cleanReply(draft.Message)
+	}
+	if draft.Message == "" {
+		return nil, errors.New("生成 PR 描述失败：LLM 返回了空内容")
+	}
+	return draft, nil
+}
+
+// prTemplate 读取配置的 PR 模板；相对路径相对于仓库根目录。未配置时返回 DefaultPRTemplate。
+func (r *Runner) prTemplate() (string, error) {
+	path := strings.TrimSpace(r.opts.PR.Template)
+	if path == "" {
+		return DefaultPRTemplate, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		root, err := gitops.GetRepoRoot()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(root, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取 PR 模板失败：%w", err)
+	}
+	return string(data), nil
+}
+
+// buildPRPrompt 构造生成 PR 描述的提示词。
+func (r *Runner) buildPRPrompt(tmpl, log string, cs *changeSet) string {
+	cfg := r.opts.PR
+
+	var b strings.Builder
+	b.WriteString(prSystemPrompt)
+	b.WriteString("\n\n要求：\n")
+	b.WriteString("- 严格按照下面模板的结构撰写，保留模板中的各级标题与顺序，把注释和占位内容替换为实际内容，不要保留注释\n")
+	b.WriteString("- 第一行是以 \"# \" 开头的一句话标题，概括整个分支的目的；模板中没有标题时也要加上\n")
+	b.WriteString("- 动机来自提交记录与代码改动本身，不要编造背景；看不出动机时简要说明改动带来的效果\n")
+	b.WriteString("- 如实指出有风险的改动（行为变化、兼容性、性能、安全、数据迁移），测试清单要具体到可以逐项验证\n")
+	if lang := strings.TrimSpace(cfg.Language); lang != "" {
+		fmt.Fprintf(&b, "- 使用 %s 撰写（模板中的标题保持原样）\n", lang)
+	}
+	if s := strings.TrimSpace(cfg.Instructions); s != "" {
+		fmt.Fprintf(&b, "- %s\n", s)
+	}
+	b.WriteString("- 只输出 PR 描述本身（Markdown），不要放在代码块中，也不要附加任何解释\n")
+
+	fmt.Fprintf(&b, "\n模板：\n\n~~~markdown\n%s\n~~~\n", strings.TrimSpace(tmpl))
+	if log != "" {
+		fmt.Fprintf(&b, "\n提交记录（从早到晚）：\n\n%s\n", log)
+	}
+	cs.writePrompt(&b)
+	return b.String()
+}
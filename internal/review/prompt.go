@@ -0,0 +1,123 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GuLuGuLuGit/review-go/internal/analysis"
+)
+
+// PromptVersion 是审查提示词模板的版本，随审查历史一起保存，也是 LLM 回复缓存 key 的一部分。
+// 修改 systemPrompt、fixInstructions 或 buildReviewPrompt 的输出格式（包括分组、依赖与整体审查的提示词）时
+// 应当递增，以便区分不同提示词下的审查结果。
+//
+// 2：提示词中加入导出 API 检查的说明与行号为 0 的诊断。
+const PromptVersion = "2"
+
+const systemPrompt = `你是一名资深 Golang 专家，擅长设计高可读性、可维护且鲁棒的 Go 代码。
+现在请你扮演“代码审查助手”，针对给定的 Git diff 进行严格的代码评审，重点关注：
+
+1. 安全性：
+   - 输入校验是否充分
+   - 是否存在潜在的注入风险、越界访问、竞争条件等
+   - 敏感信息（如密钥、token、密码）是否有泄露风险
+
+2. 错误处理：
+   - 错误是否被忽略或吞掉
+   - 错误信息是否清晰、能帮助定位问题
+   - 是否合理使用 error wrapping 以及日志
+
+3. 性能与资源使用：
+   - 算法与数据结构是否合理
+   - 是否存在明显的多余分配或重复计算
+   - I/O、网络、并发是否可能成为瓶颈
+
+请以 Markdown 格式输出审查结果，建议结构示例：
+
+## 总体评价
+- 简要评价这次变更的整体质量。
+
+## 主要风险与问题
+- 按严重程度列出主要问题，并引用相关代码片段或行号（如果 diff 中有）。
+
+## 优化建议
+- 给出可以改进的地方，包括安全、错误处理和性能方面的具体建议。
+
+## 认可的优点
+- 指出本次改动中值得保留或学习的写法。
+
+回复时只需要给出审查内容，无需重复贴出完整 diff。
+
+在 Markdown 报告之后，请追加一个语言标记为 findings 的代码块，用 JSON 数组列出“主要风险与问题”中的每一条，
+字段为 severity（high / medium / low / info）、line（新文件中的行号，未知时为 0）、title（一句话概括）、detail（简要说明），例如：
+
+` + "```findings" + `
+[{"severity": "high", "line": 42, "title": "未检查 Close 返回的错误", "detail": "写文件后 Close 失败会导致数据丢失。"}]
+` + "```" + `
+
+没有问题时输出空数组 []。`
+
+// fixInstructions 在修复模式下追加到系统说明之后，要求模型为问题附带补丁。
+const fixInstructions = `
+
+修复模式：对于能够明确修复的问题，请在 findings 的对应条目中额外给出 patch 字段，
+内容为针对该文件暂存区版本的标准 unified diff（包含 "--- a/<路径>"、"+++ b/<路径>" 文件头和至少 3 行上下文），
+要求可以直接通过 git apply 应用。每个 patch 只修复对应的一个问题；无法给出可靠补丁时省略该字段，不要臆造代码。`
+
+// promptInput 汇总构造审查提示词所需的全部信息。
+//
+// - Diff: 文件在暂存区中的 diff
+// - Diagnostics: 落在变更行上的静态分析诊断
+// - Fix: 是否开启修复模式
+// - Content: 文件在暂存区中的完整内容，仅修复模式下提供，供模型生成上下文准确的补丁
+type promptInput struct {
+	Diff        string
+	Diagnostics []analysis.Diagnostic
+	Fix         bool
+	Content     string
+}
+
+// buildReviewPrompt 根据 Git diff 以及变更行上的静态分析诊断构造发送给 LLM 的审查提示词。
+// 系统说明与用户请求合并为一条消息，以便通过通用的 Chat 接口发送。
+func buildReviewPrompt(in promptInput) string {
+	diff := strings.TrimSpace(in.Diff)
+	if diff == "" {
+		return "暂存区 diff 为空，无需审查。"
+	}
+
+	system := systemPrompt
+	var userPrompt string
+	if in.Fix {
+		system += fixInstructions
+		userPrompt = fmt.Sprintf(
+			"请审查以下 Git diff，并按照上述要求返回 Markdown 格式的审查报告，可修复的问题请在 findings 中附带 patch：\n\n```diff\n%s\n```",
+			diff,
+		)
+		if in.Content != "" {
+			userPrompt += fmt.Sprintf("\n\n该文件在暂存区中的完整内容如下，补丁的上下文行必须与之一致：\n\n```go\n%s\n```", in.Content)
+		}
+	} else {
+		userPrompt = fmt.Sprintf(
+			"请审查以下 Git diff（只读即可，不需要给出可直接应用的 patch），并按照上述要求返回 Markdown 格式的审查报告：\n\n```diff\n%s\n```",
+			diff,
+		)
+	}
+
+	if len(in.Diagnostics) > 0 {
+		var b strings.Builder
+		b.WriteString("\n\n以下是静态分析工具在本次变更行上报告的问题。请在审查中逐条解释其含义与影响，判断是否为误报，并结合其他问题排定优先级：\n\n")
+		for _, d := range in.Diagnostics {
+			if d.Line > 0 {
+				fmt.Fprintf(&b, "- [%s] 第 %d 行：%s\n", d.Tool, d.Line, d.Message)
+			} else {
+				fmt.Fprintf(&b, "- [%s] %s\n", d.Tool, d.Message)
+			}
+		}
+		if hasAPIDiagnostics(in.Diagnostics) {
+			b.WriteString(apiNote)
+		}
+		userPrompt += b.String()
+	}
+
+	return system + "\n\n" + userPrompt
+}
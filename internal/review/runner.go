@@ -0,0 +1,430 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/analysis"
+	"github.com/GuLuGuLuGit/review-go/internal/cache"
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+	"github.com/GuLuGuLuGit/review-go/internal/deps"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+	"github.com/GuLuGuLuGit/review-go/internal/policy"
+	"github.com/GuLuGuLuGit/review-go/internal/redact"
+	"github.com/GuLuGuLuGit/review-go/internal/usage"
+)
+
+// Options 控制一次审查运行的行为。
+type Options struct {
+	// Analysis 指定在调用 LLM 之前需要运行的静态分析工具。
+	Analysis analysis.Options
+
+	// Fix 开启修复模式：要求 LLM 为问题附带 unified diff 补丁，并用 git apply --check 校验。
+	Fix bool
+
+	// VerifyFixes 在临时 worktree 中应用补丁并执行 go build ./...，验证补丁至少能够编译。
+	VerifyFixes bool
+	// VerifyTests 在编译通过后额外对补丁所在的包执行 go test。
+	VerifyTests bool
+	// DropFailedFixes 丢弃验证失败的补丁，而不仅仅是标记出来。
+	DropFailedFixes bool
+
+	// ShowDismissed 显示此前被标记为已忽略的问题（默认隐藏）。
+	ShowDismissed bool
+
+	// Recorder 接收每个文件的审查结果（包括重新审查），用于保存审查历史；为 nil 时不记录。
+	Recorder Recorder
+
+	// Cache 缓存 LLM 的审查回复，相同的提供商、模型、提示词版本与 diff 直接复用；为 nil 时不缓存。
+	Cache *cache.Cache
+
+	// Redactor 用于报告每个文件发送前被脱敏的密钥，并在 block 模式下拒绝审查该文件；
+	// 实际的替换由包装在 provider 外层的 redact.Provider 完成。为 nil 时不报告。
+	Redactor *redact.Redactor
+
+	// Policy 是按路径的外发策略：不允许发送给当前提供商的文件在查缓存之前就被拒绝，
+	// 并以包装了 policy.ErrDenied 的错误报告出来；实际发送时由 policy.Provider 再次检查。
+	Policy *policy.Policy
+
+	// DryRun 表示 provider 是 ai.DryRunProvider：回复是将要发送的请求本身，
+	// 直接作为审查报告展示，不从中解析问题，也不校验补丁。
+	DryRun bool
+
+	// Base 与 Head 非空时审查这两个提交之间的变更（例如 pre-push 钩子中将要推送的提交），
+	// 而不是暂存区。此时除导出 API 检查外不运行静态分析：分析工具作用于工作区，其内容与 Head 不一定一致。
+	Base string
+	Head string
+
+	// Commit 是生成提交信息（见 CommitMessage）时使用的语言与风格。
+	Commit config.CommitConfig
+
+	// PR 是生成 PR 描述（见 PRDescription）时使用的语言与模板。
+	PR config.PRConfig
+
+	// Overall 在逐个文件审查之后再对全部变更做一次整体审查（见 ReviewOverall），查找跨文件的问题。
+	Overall bool
+
+	// MaxFiles 大于 0 时只审查风险评分（见 Triage）最高的 MaxFiles 个文件。
+	MaxFiles int
+
+	// Group 为审查单元的划分方式（见 Units）：GroupPackage 按包目录、GroupModule 按 go.mod 模块
+	// 把有变更的文件合在一起审查；为空或 GroupFile 时逐个文件审查。
+	Group string
+
+	// Deps 开启后，go.mod / go.sum 有变化的模块各自作为一个审查单元（见 DepsUnits）：
+	// 先比较依赖的版本、replace 指令、已知漏洞与许可证，再把检查结果连同 diff 发送给 LLM 解释。
+	Deps bool
+	// DepsOptions 是依赖检查使用的离线漏洞库与模块缓存。
+	DepsOptions deps.Options
+
+	// API 开启后，比较有变更的包在审查起点与最新版本之间的导出 API，
+	// 把删除或不兼容地修改了的导出标识符作为高严重程度的 apidiff 诊断（见 Analyze）交给 LLM 解释。
+	API bool
+}
+
+// Recorder 是审查结果的记录器，例如 history 包中的审查历史。
+type Recorder interface {
+	Record(rev *FileReview) error
+}
+
+// FileReview 是单个文件（分组审查时为一个审查单元，见 Unit）的审查结果。
+//
+// - Diff: 该文件在暂存区（或 Options.Base 与 Head 之间）的 diff
+// - Markdown: LLM 返回的审查报告（已去掉结构化的 findings 代码块）
+// - Findings: LLM 与静态分析工具给出的结构化问题，按严重程度排序
+// - Cached: LLM 回复是否来自缓存
+// - Usage: 审查该文件的 LLM 请求消耗的 token 与费用，命中缓存时为零
+// - Redactions: 发送给 LLM 之前从该文件内容中脱敏的密钥
+// - Files: 整体审查（File 为 OverallFile）或分组审查（File 为单元的 key）包含的文件，单个文件的审查为空
+// - Risk: 整体审查给出的整体风险评级，单个文件的审查或无法解析时为空
+type FileReview struct {
+	File       string
+	Diff       string
+	Markdown   string
+	Findings   []Finding
+	Cached     bool
+	Usage      ai.Usage
+	Redactions []redact.Match
+	Files      []string
+	Risk       Severity
+}
+
+// Runner 把 Git、静态分析与 LLM 调用串联起来，是 TUI 与其他入口共用的审查流程。
+//
+// decisions 为用户对问题做出的决定记录；读取失败时为 nil，decisionsErr 记录原因，
+// 此时审查照常进行，只是无法标记问题。
+type Runner struct {
+	provider ai.LLMProvider
+	opts     Options
+
+	decisions    *DecisionStore
+	decisionsErr error
+}
+
+// NewRunner 创建一个 Runner。provider 通过依赖注入传入，便于在不同 AI 提供商之间切换。
+func NewRunner(provider ai.LLMProvider, opts Options) *Runner {
+	decisions, err := LoadDecisions()
+	return &Runner{
+		provider:     provider,
+		opts:         opts,
+		decisions:    decisions,
+		decisionsErr: err,
+	}
+}
+
+// Decide 记录用户对问题 f 的决定（status 为空时清除已有决定），并同步更新 f.Decision。
+func (r *Runner) Decide(f *Finding, status DecisionStatus, reason string) error {
+	if r.decisions == nil {
+		return fmt.Errorf("决定记录不可用：%w", r.decisionsErr)
+	}
+
+	d := Decision{
+		Status: status,
+		Reason: strings.TrimSpace(reason),
+		File:   f.File,
+		Line:   f.Line,
+		Title:  f.Title,
+		At:     time.Now(),
+	}
+	if err := r.decisions.Set(f.Fingerprint, d); err != nil {
+		return err
+	}
+
+	if status == "" {
+		f.Decision = nil
+	} else {
+		f.Decision = &d
+	}
+	return nil
+}
+
+// ChangedFiles 返回暂存区（或 Options.Base 与 Head 之间）有变更的 .go 文件列表。
+func (r *Runner) ChangedFiles() ([]string, error) {
+	if base, head := r.Range(); head != "" {
+		return gitops.GetRangeChangedFiles(base, head)
+	}
+	return gitops.GetChangedFiles()
+}
+
+// Range 返回审查的提交范围；审查暂存区时都为空。
+func (r *Runner) Range() (base, head string) {
+	return r.opts.Base, r.opts.Head
+}
+
+// fileDiff 返回 file 在审查范围内的 diff（--unified=0）。
+func (r *Runner) fileDiff(file string) (string, error) {
+	if base, head := r.Range(); head != "" {
+		return gitops.GetFileRangeDiff(base, head, file)
+	}
+	return gitops.GetFileStagedDiff(file)
+}
+
+// fileContent 返回 file 在审查范围内的最新内容：暂存区或 Head 提交中的内容。
+func (r *Runner) fileContent(file string) (string, error) {
+	if _, head := r.Range(); head != "" {
+		return gitops.GetFileContentAt(head, file)
+	}
+	return gitops.GetStagedFileContent(file)
+}
+
+// Analyze 对 files 所在的包运行静态分析，只保留落在各文件暂存区变更行上的诊断；
+// 开启 Options.API 时还包含导出 API 的不兼容变化（见 checkAPI）。
+//
+// 静态分析只是辅助信息：获取仓库根目录或 diff 失败时返回空结果，不影响后续 LLM 审查；
+// 审查提交范围时只比较导出 API，不运行分析工具（见 Options.Base）。
+func (r *Runner) Analyze(files []string) map[string][]analysis.Diagnostic {
+	// 导出 API 的比较读取 git 中的内容，与工作区无关，审查提交范围时同样进行
+	result := r.checkAPI(files)
+	if _, head := r.Range(); head != "" {
+		return result
+	}
+
+	root, err := gitops.GetRepoRoot()
+	if err != nil {
+		return result
+	}
+
+	all := analysis.Run(root, files, r.opts.Analysis)
+	for _, f := range files {
+		diags := all[f]
+		if len(diags) == 0 {
+			continue
+		}
+
+		diff, err := gitops.GetFileStagedDiff(f)
+		if err != nil {
+			continue
+		}
+
+		changed := gitops.ParseChangedLines(diff)
+		for _, d := range diags {
+			if changed[d.Line] {
+				result[f] = append(result[f], d)
+			}
+		}
+	}
+
+	return result
+}
+
+// ReviewFile 审查单个文件。diags 为 Analyze 针对该文件给出的诊断，可以为空。
+// 开启缓存时，相同输入直接复用缓存中的 LLM 回复。
+func (r *Runner) ReviewFile(file string, diags []analysis.Diagnostic) (*FileReview, error) {
+	return r.reviewFile(file, diags, false)
+}
+
+// ReviewFileFresh 与 ReviewFile 相同，但总是重新请求 LLM（结果仍会写入缓存），
+// 用于用户在代码未变化时主动要求重新审查。
+func (r *Runner) ReviewFileFresh(file string, diags []analysis.Diagnostic) (*FileReview, error) {
+	return r.reviewFile(file, diags, true)
+}
+
+func (r *Runner) reviewFile(file string, diags []analysis.Diagnostic, fresh bool) (*FileReview, error) {
+	if r == nil || r.provider == nil {
+		return nil, errors.New("LLM Provider 未初始化")
+	}
+
+	diff, err := r.fileDiff(file)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件 %s 的 diff 失败：%w", file, err)
+	}
+
+	// 完整内容用于计算问题指纹，修复模式下还用于帮助模型写出上下文正确的补丁；
+	// 读取失败时退化为仅基于 diff。
+	content, _ := r.fileContent(file)
+	contents := map[string]string{file: content}
+
+	if err := r.opts.Policy.Check(r.Identity().Provider, file); err != nil {
+		return r.diagnosticsReview(file, diff, diags, contents, llmFailedNote(err)), err
+	}
+
+	in := promptInput{Diff: diff, Diagnostics: diags, Fix: r.opts.Fix}
+	if r.opts.Fix {
+		in.Content = content
+	}
+
+	redactions, err := r.redactionReport(diff+"\n"+in.Content, fmt.Sprintf("审查文件 %s 失败", file))
+	if err != nil {
+		return r.diagnosticsReview(file, diff, diags, contents, llmFailedNote(err)), err
+	}
+
+	reply, cached, used, err := r.chatCached(file, in, fresh)
+	if err != nil {
+		err = fmt.Errorf("审查文件 %s 失败：%w", file, err)
+		return r.diagnosticsReview(file, diff, diags, contents, llmFailedNote(err)), err
+	}
+
+	markdown, findings := reply, []Finding(nil)
+	if !r.opts.DryRun {
+		// 试运行的回复中包含提示词里的 findings 示例，不能当作真实问题解析
+		markdown, findings = extractFindings(reply, file, nil)
+	}
+	for i := range findings {
+		if findings[i].Patch == "" {
+			continue
+		}
+		if err := gitops.CheckPatch(findings[i].Patch, true); err != nil {
+			findings[i].PatchErr = err.Error()
+		}
+	}
+	if r.opts.VerifyFixes {
+		r.verifyFixes(findings)
+	}
+	findings = append(findings, findingsFromDiagnostics(diags)...)
+	sortFindings(findings)
+	findings = applyDecisions(r.decisions, findings, content, !r.opts.ShowDismissed)
+
+	rev := &FileReview{
+		File:       file,
+		Diff:       diff,
+		Markdown:   markdown,
+		Findings:   findings,
+		Cached:     cached,
+		Usage:      used,
+		Redactions: redactions,
+	}
+	if r.opts.Recorder != nil {
+		// 历史记录只是附加功能，写入失败不影响本次审查结果。
+		_ = r.opts.Recorder.Record(rev)
+	}
+	return rev, nil
+}
+
+// diagnosticsReview 返回只包含 diags 对应问题的 FileReview，报告为 note；diags 为空时返回 nil。
+// 静态分析与导出 API 检查不依赖 LLM，LLM 审查未能完成（外发策略不允许发送、密钥拦截、预算用尽或请求失败）
+// 或文件因 --max-files 未审查时，用它保留这些问题。contents 的含义见 applyDecisionsByFile。
+func (r *Runner) diagnosticsReview(key, diff string, diags []analysis.Diagnostic, contents map[string]string, note string) *FileReview {
+	if len(diags) == 0 {
+		return nil
+	}
+	findings := findingsFromDiagnostics(diags)
+	sortFindings(findings)
+	return &FileReview{
+		File:     key,
+		Diff:     diff,
+		Markdown: note,
+		Findings: r.applyDecisionsByFile(findings, contents),
+	}
+}
+
+// llmFailedNote 返回 LLM 审查因 err 未能完成时 diagnosticsReview 使用的报告。
+func llmFailedNote(err error) string {
+	return fmt.Sprintf("_未能完成 LLM 审查：%v_\n\n以下只列出静态分析与导出 API 检查发现的问题。", err)
+}
+
+// redactionReport 与 redact.Provider 使用同一套规则检查将要发送的 text：block 模式下在查缓存之前就拒绝，
+// 返回以 label 为前缀的错误；其他模式下返回会被脱敏的密钥，用于在审查结果中报告。
+func (r *Runner) redactionReport(text, label string) ([]redact.Match, error) {
+	if r.opts.Redactor == nil {
+		return nil, nil
+	}
+	if err := r.opts.Redactor.Check(text); err != nil {
+		return nil, fmt.Errorf("%s：%w", label, err)
+	}
+	_, matches := r.opts.Redactor.Redact(text)
+	return matches, nil
+}
+
+// chatCached 发送 file 的审查提示词并返回回复，开启缓存时先查缓存。fresh 为 true 时跳过读取缓存。
+//
+// 缓存 key 由提供商、模型、PromptVersion 以及用规范化后的 diff 生成的完整提示词组成，
+// 因此诊断信息、修复模式等任何会改变提示词的输入都会得到不同的 key。
+func (r *Runner) chatCached(file string, in promptInput, fresh bool) (reply string, cached bool, used ai.Usage, err error) {
+	req := ai.Request{
+		Messages: []ai.Message{{Role: ai.RoleUser, Content: buildReviewPrompt(in)}},
+		Files:    []string{file},
+	}
+
+	key := ""
+	if r.opts.Cache != nil {
+		id := r.Identity()
+		keyed := in
+		keyed.Diff = normalizeDiff(in.Diff)
+		key = cache.Key(id.Provider, id.Model, PromptVersion, buildReviewPrompt(keyed))
+	}
+	return r.sendCached(req, key, fresh)
+}
+
+// sendCached 发送 req 并返回回复。key 非空且开启了缓存时先查缓存（fresh 为 true 时跳过），
+// 请求成功后把回复写入缓存。
+func (r *Runner) sendCached(req ai.Request, key string, fresh bool) (reply string, cached bool, used ai.Usage, err error) {
+	if r.opts.Cache == nil || key == "" {
+		reply, used, err = ai.Send(r.provider, req)
+		return reply, false, used, err
+	}
+
+	if !fresh {
+		if data, ok := r.opts.Cache.Get(key); ok {
+			return string(data), true, ai.Usage{}, nil
+		}
+	}
+
+	reply, used, err = ai.Send(r.provider, req)
+	if err != nil {
+		return "", false, used, err
+	}
+	// 缓存只是优化，写入失败不影响本次结果
+	_ = r.opts.Cache.Put(key, []byte(reply))
+	return reply, false, used, nil
+}
+
+// normalizeDiff 去掉 diff 中与代码内容无关、却会让相同改动得到不同 key 的部分：
+// index 行中的 blob 哈希与文件模式、Windows 换行符以及行尾多余的空行。
+func normalizeDiff(diff string) string {
+	diff = strings.ReplaceAll(diff, "\r\n", "\n")
+
+	lines := strings.Split(diff, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if strings.HasPrefix(l, "index ") {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n")
+}
+
+// Usage 返回本次运行累计的 LLM 用量。provider 不是 usage.Meter 时无法统计，ok 为 false。
+func (r *Runner) Usage() (totals usage.Totals, ok bool) {
+	if r == nil {
+		return usage.Totals{}, false
+	}
+	m, ok := r.provider.(*usage.Meter)
+	if !ok {
+		return usage.Totals{}, false
+	}
+	return m.Totals(), true
+}
+
+// DryRun 报告本次运行是否为试运行。
+func (r *Runner) DryRun() bool {
+	return r != nil && r.opts.DryRun
+}
+
+// Identity 返回当前使用的提供商与模型。
+func (r *Runner) Identity() ai.Identity {
+	return ai.IdentityOf(r.provider)
+}
@@ -0,0 +1,168 @@
+package review
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+const (
+	// verifyTimeout 是单次 go build / go test 的超时时间。
+	verifyTimeout = 5 * time.Minute
+
+	// maxVerifyOutput 限制保存的失败输出长度，只保留末尾部分（编译错误通常在最后）。
+	maxVerifyOutput = 4000
+)
+
+// 验证失败所处的阶段。
+const (
+	VerifyStageSetup = "setup"
+	VerifyStageApply = "apply"
+	VerifyStageBuild = "build"
+	VerifyStageTest  = "test"
+)
+
+// Verification 是补丁在临时 worktree 中的编译 / 测试验证结果。
+//
+// - Passed: 补丁应用、编译以及（如果运行了）测试全部通过
+// - Tested: 是否运行了 go test
+// - Stage: 失败时所处的阶段（VerifyStage*），通过时为空
+// - Output: 失败时命令的输出（截断）
+type Verification struct {
+	Passed bool
+	Tested bool
+	Stage  string
+	Output string
+}
+
+// Summary 返回一句适合展示在问题列表中的验证结论。
+func (v *Verification) Summary() string {
+	switch {
+	case v == nil:
+		return ""
+	case v.Passed && v.Tested:
+		return "已通过编译与测试"
+	case v.Passed:
+		return "已通过编译"
+	case v.Stage == VerifyStageSetup:
+		return "未能验证"
+	case v.Stage == VerifyStageApply:
+		return "在临时 worktree 中应用失败"
+	case v.Stage == VerifyStageTest:
+		return "测试未通过"
+	default:
+		return "编译失败"
+	}
+}
+
+// verifyFixes 在一个检出暂存区快照的临时 worktree 中逐个验证通过了 git apply --check 的补丁：
+// 应用补丁、在所属模块中执行 go build ./...，并按需对补丁所在的包执行 go test。
+//
+// 每个补丁验证完都会把 worktree 还原，保证补丁之间互不影响。
+// 开启 DropFailedFixes 时，应用、编译或测试失败的补丁会被丢弃，但验证结果仍然保留以便展示原因。
+func (r *Runner) verifyFixes(findings []Finding) {
+	var pending []int
+	for i := range findings {
+		if findings[i].HasValidPatch() {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	wt, err := gitops.NewScratchWorktree()
+	if err != nil {
+		for _, i := range pending {
+			findings[i].Verification = &Verification{Stage: VerifyStageSetup, Output: err.Error()}
+		}
+		return
+	}
+	defer wt.Remove()
+
+	for _, i := range pending {
+		v := r.verifyPatch(wt, findings[i].File, findings[i].Patch)
+		findings[i].Verification = v
+
+		if !v.Passed && v.Stage != VerifyStageSetup && r.opts.DropFailedFixes {
+			findings[i].Patch = ""
+		}
+	}
+}
+
+// verifyPatch 在 wt 中验证单个补丁，结束后把 wt 还原为暂存区快照。
+func (r *Runner) verifyPatch(wt *gitops.ScratchWorktree, file, patch string) *Verification {
+	defer func() { _ = wt.Reset() }()
+
+	if err := wt.Apply(patch); err != nil {
+		return &Verification{Stage: VerifyStageApply, Output: err.Error()}
+	}
+
+	pkgDir := filepath.Join(wt.Dir, filepath.FromSlash(path.Dir(file)))
+	modDir := findModuleDir(wt.Dir, pkgDir)
+	if modDir == "" {
+		return &Verification{Stage: VerifyStageSetup, Output: "未找到 " + file + " 所属的 go.mod"}
+	}
+
+	if out, err := runGo(modDir, "build", "./..."); err != nil {
+		return &Verification{Stage: VerifyStageBuild, Output: out}
+	}
+
+	if !r.opts.VerifyTests {
+		return &Verification{Passed: true}
+	}
+
+	rel, err := filepath.Rel(modDir, pkgDir)
+	if err != nil {
+		return &Verification{Stage: VerifyStageSetup, Output: err.Error()}
+	}
+	if out, err := runGo(modDir, "test", "./"+filepath.ToSlash(rel)); err != nil {
+		return &Verification{Tested: true, Stage: VerifyStageTest, Output: out}
+	}
+
+	return &Verification{Passed: true, Tested: true}
+}
+
+// findModuleDir 从 dir 开始向上查找包含 go.mod 的目录，最多查找到 root 为止。
+func findModuleDir(root, dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		if dir == root {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || !strings.HasPrefix(parent, root) {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// runGo 在 dir 下执行 go 子命令，返回截断后的合并输出。
+func runGo(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+
+	output := strings.TrimSpace(string(out))
+	if len(output) > maxVerifyOutput {
+		output = "..." + output[len(output)-maxVerifyOutput:]
+	}
+	if err != nil && output == "" {
+		output = err.Error()
+	}
+
+	return output, err
+}
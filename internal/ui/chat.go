@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// chatSession 保存某个文件的追问对话。
+//
+// - messages: 完整的对话历史，以 review.ChatSeed 生成的系统消息开头
+// - waiting: 是否正在等待模型回复
+// - err: 最近一次请求的错误（如果有）
+type chatSession struct {
+	messages []ai.Message
+	waiting  bool
+	err      error
+}
+
+// chatReplyMsg 是追问请求完成后发送给 UI 的消息。
+type chatReplyMsg struct {
+	file  string
+	reply string
+	err   error
+}
+
+// transcriptSavedMsg 是保存对话记录完成后发送给 UI 的消息。
+type transcriptSavedMsg struct {
+	path string
+	err  error
+}
+
+var (
+	chatInputStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(0, 1)
+
+	chatHintStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("244"))
+)
+
+// newChatInput 创建追问输入框。
+func newChatInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "就这份审查继续提问，回车发送"
+	ti.Prompt = "› "
+	ti.CharLimit = 2000
+	return ti
+}
+
+// chatCmd 在后台发送一轮关于 rev 的追问，完成后发送 chatReplyMsg。
+func chatCmd(runner *review.Runner, rev *review.FileReview, messages []ai.Message) tea.Cmd {
+	return func() tea.Msg {
+		reply, err := runner.Chat(rev, messages)
+		return chatReplyMsg{file: rev.File, reply: reply, err: err}
+	}
+}
+
+// saveTranscriptCmd 在后台保存审查报告与对话记录，完成后发送 transcriptSavedMsg。
+func saveTranscriptCmd(rev *review.FileReview, messages []ai.Message) tea.Cmd {
+	return func() tea.Msg {
+		path, err := review.SaveTranscript(rev, messages)
+		return transcriptSavedMsg{path: path, err: err}
+	}
+}
+
+// openChat 为当前文件打开追问面板，首次打开时用该文件的 diff 与审查结果初始化对话。
+func (m *Model) openChat() tea.Cmd {
+	rev := m.currentReview()
+	if rev == nil {
+		m.status = "该文件暂无审查结果，无法追问"
+		return nil
+	}
+
+	if _, ok := m.chats[rev.File]; !ok {
+		m.chats[rev.File] = &chatSession{messages: review.ChatSeed(rev)}
+	}
+
+	m.chatOpen = true
+	m.chatInput.Reset()
+	return m.chatInput.Focus()
+}
+
+// updateChat 处理追问面板打开时的按键：回车发送、Esc 关闭、Ctrl+S 保存，其余交给输入框。
+func (m Model) updateChat(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rev := m.currentReview()
+	if rev == nil {
+		m.chatOpen = false
+		return m, nil
+	}
+	session := m.chats[rev.File]
+
+	switch msg.String() {
+	case "esc":
+		m.chatOpen = false
+		m.chatInput.Blur()
+		return m, nil
+
+	case "ctrl+s":
+		return m, saveTranscriptCmd(rev, session.messages)
+
+	case "enter":
+		question := strings.TrimSpace(m.chatInput.Value())
+		if question == "" || session.waiting {
+			return m, nil
+		}
+
+		session.messages = append(session.messages, ai.Message{Role: ai.RoleUser, Content: question})
+		session.waiting = true
+		session.err = nil
+		m.chatInput.Reset()
+
+		// 拷贝一份历史交给后台任务，避免与后续追加的消息共享底层数组。
+		history := append([]ai.Message(nil), session.messages...)
+		return m, chatCmd(m.runner, rev, history)
+	}
+
+	var cmd tea.Cmd
+	m.chatInput, cmd = m.chatInput.Update(msg)
+	return m, cmd
+}
+
+// handleChatReply 把模型回复追加到对应文件的对话历史中。
+func (m *Model) handleChatReply(msg chatReplyMsg) {
+	session, ok := m.chats[msg.file]
+	if !ok {
+		return
+	}
+
+	session.waiting = false
+	if msg.err != nil {
+		session.err = msg.err
+		// 请求失败时撤回这次提问，方便用户修改后重新发送。
+		if n := len(session.messages); n > 0 && session.messages[n-1].Role == ai.RoleUser {
+			m.chatInput.SetValue(session.messages[n-1].Content)
+			session.messages = session.messages[:n-1]
+		}
+		return
+	}
+
+	session.messages = append(session.messages, ai.Message{Role: ai.RoleAssistant, Content: msg.reply})
+}
+
+// viewChat 渲染追问面板：对话记录在上，输入框在下。对话较长时只保留能放下的最后几行。
+func (m Model) viewChat(width, height int) string {
+	rev := m.currentReview()
+	if rev == nil {
+		return ""
+	}
+	session := m.chats[rev.File]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## 追问：%s\n", displayName(rev.File))
+	for _, msg := range session.messages {
+		switch msg.Role {
+		case ai.RoleUser:
+			fmt.Fprintf(&b, "\n**你：** %s\n", msg.Content)
+		case ai.RoleAssistant:
+			fmt.Fprintf(&b, "\n**助手：**\n\n%s\n", msg.Content)
+		}
+	}
+	if session.waiting {
+		b.WriteString("\n_助手正在回复..._\n")
+	}
+	if session.err != nil {
+		fmt.Fprintf(&b, "\n_请求失败：%v_\n", session.err)
+	}
+
+	m.chatInput.Width = width - 8
+	input := chatInputStyle.Width(width - 4).Render(m.chatInput.View())
+	hint := chatHintStyle.Render("回车 发送  Ctrl+S 保存审查与对话  Esc 返回审查报告")
+
+	transcript := renderMarkdown(b.String(), width)
+	if height > 0 {
+		transcript = tailLines(transcript, height-lipgloss.Height(input)-lipgloss.Height(hint))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, transcript, input, hint)
+}
+
+// tailLines 只保留 s 的最后 n 行。
+func tailLines(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[len(lines)-n:], "\n")
+}
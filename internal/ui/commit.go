@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// commitMsgGeneratedMsg 是根据暂存区生成提交信息完成后发送给 UI 的消息。
+type commitMsgGeneratedMsg struct {
+	draft *review.Draft
+	err   error
+}
+
+// committedMsg 是 git commit 退出、TUI 恢复后发送给 UI 的消息。subject 为提交信息的标题。
+type committedMsg struct {
+	subject string
+	err     error
+}
+
+// generateCommitMsgCmd 在后台根据暂存区的全部变更生成提交信息，完成后发送 commitMsgGeneratedMsg。
+func generateCommitMsgCmd(runner *review.Runner) tea.Cmd {
+	return func() tea.Msg {
+		if runner == nil {
+			return commitMsgGeneratedMsg{err: errors.New("审查流程未初始化")}
+		}
+		draft, err := runner.CommitMessage()
+		return commitMsgGeneratedMsg{draft: draft, err: err}
+	}
+}
+
+// startCommit 开始生成提交信息；生成后交给 git commit 在编辑器中编辑并提交。
+func (m *Model) startCommit() tea.Cmd {
+	switch {
+	case m.runner.DryRun():
+		m.status = "试运行中不生成提交信息"
+		return nil
+	case m.committing:
+		m.status = "正在生成提交信息，请稍候"
+		return nil
+	}
+
+	m.committing = true
+	m.status = "正在根据暂存区的全部变更生成提交信息..."
+	return generateCommitMsgCmd(m.runner)
+}
+
+// handleCommitMsgGenerated 把生成的提交信息写入临时文件，挂起 TUI 执行 git commit -e -F：
+// git 在编辑器中打开这条信息，保存退出后完成提交，清空内容则放弃提交。
+//
+// 暂存区刚刚在 TUI 中审查过，因此设置 REVIEW_GO_SKIP=1 跳过 review-go 的 pre-commit 钩子，
+// 其他钩子照常执行。
+func (m *Model) handleCommitMsgGenerated(msg commitMsgGeneratedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.committing = false
+		m.status = fmt.Sprintf("生成提交信息失败：%v", msg.err)
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "review-go-commit-*.txt")
+	if err == nil {
+		_, err = f.WriteString(msg.draft.Message + "\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		m.committing = false
+		m.status = fmt.Sprintf("写入提交信息失败：%v", err)
+		return nil
+	}
+
+	path := f.Name()
+	subject, _, _ := strings.Cut(msg.draft.Message, "\n")
+	cmd := exec.Command("git", "commit", "-e", "-F", path)
+	cmd.Env = append(os.Environ(), "REVIEW_GO_SKIP=1")
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		os.Remove(path)
+		return committedMsg{subject: subject, err: err}
+	})
+}
+
+// handleCommitted 在 git commit 结束后更新状态栏，提交成功时刷新暂存区文件列表。
+func (m *Model) handleCommitted(msg committedMsg) tea.Cmd {
+	m.committing = false
+	if msg.err != nil {
+		m.status = fmt.Sprintf("未提交：%v", msg.err)
+		return nil
+	}
+
+	cmd := m.refreshFiles("", nil)
+	m.status = fmt.Sprintf("已提交：%s", msg.subject)
+	return cmd
+}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// newReasonInput 创建忽略问题时填写原因的输入框。
+func newReasonInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "忽略原因："
+	ti.Placeholder = "可选，回车确认，Esc 取消"
+	ti.CharLimit = 200
+	return ti
+}
+
+// startMarking 为当前选中的问题打开标记提示。
+func (m *Model) startMarking() {
+	if m.currentFinding() == nil {
+		m.status = "当前文件没有可标记的问题"
+		return
+	}
+	m.marking = true
+	m.status = "标记问题：a 接受  d 忽略  w 不修复  u 清除标记  其他键取消"
+}
+
+// updateMarking 处理标记提示打开时的按键。选择忽略时继续打开原因输入框。
+func (m Model) updateMarking(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.marking = false
+
+	switch msg.String() {
+	case "a":
+		m.decide(review.DecisionAccepted, "")
+	case "w":
+		m.decide(review.DecisionWontFix, "")
+	case "u":
+		m.decide("", "")
+	case "d":
+		m.reasoning = true
+		m.reasonInput.Reset()
+		m.status = ""
+		return m, tea.Batch(m.resizeViewport(), m.reasonInput.Focus())
+	default:
+		m.status = ""
+	}
+	return m, nil
+}
+
+// updateReason 处理忽略原因输入框打开时的按键：回车确认忽略，Esc 取消。
+func (m Model) updateReason(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.reasoning = false
+		m.reasonInput.Blur()
+		m.status = ""
+		return m, m.resizeViewport()
+
+	case "enter":
+		m.reasoning = false
+		m.reasonInput.Blur()
+		m.decide(review.DecisionDismissed, m.reasonInput.Value())
+		return m, m.resizeViewport()
+	}
+
+	var cmd tea.Cmd
+	m.reasonInput, cmd = m.reasonInput.Update(msg)
+	return m, cmd
+}
+
+// decide 保存对当前选中问题的决定并刷新展示。写入的只是一个小的本地文件，因此直接在主循环中完成。
+func (m *Model) decide(status review.DecisionStatus, reason string) {
+	f := m.currentFinding()
+	if f == nil || m.runner == nil {
+		return
+	}
+
+	if err := m.runner.Decide(f, status, reason); err != nil {
+		m.status = fmt.Sprintf("保存标记失败：%v", err)
+		return
+	}
+
+	switch status {
+	case "":
+		m.status = "已清除该问题的标记"
+	case review.DecisionDismissed:
+		m.status = "已忽略：之后针对相同代码的运行中不再显示（--show-dismissed 可显示）"
+	default:
+		m.status = fmt.Sprintf("已标记为%s", status.Label())
+	}
+	m.refreshReview()
+	m.scrollToFinding()
+}
@@ -0,0 +1,300 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// diffContextLines 是 diff 面板中每个 hunk 前后保留的上下文行数。
+const diffContextLines = 5
+
+// diffLine 是 diff 面板中的一行。
+//
+// - kind: '+' 新增、'-' 删除、' ' 上下文、'@' hunk 头
+// - oldLine / newLine: 旧文件 / 新文件中的行号，不存在时为 0
+type diffLine struct {
+	kind    byte
+	oldLine int
+	newLine int
+	text    string
+}
+
+// diffLoadedMsg 是读取某个文件带上下文的 diff 完成后发送给 UI 的消息。
+type diffLoadedMsg struct {
+	file  string
+	lines []diffLine
+	err   error
+}
+
+var (
+	diffBoxStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240")).
+			Padding(0, 1)
+
+	gutterStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+
+	hunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39"))
+
+	addSignStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("2")).
+			Bold(true)
+
+	delSignStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("1")).
+			Bold(true)
+
+	selectedMarkerStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("229")).
+				Background(lipgloss.Color("57")).
+				Bold(true)
+
+	// severityColors 是 gutter 中问题标记按严重程度使用的颜色。
+	severityColors = map[review.Severity]lipgloss.Color{
+		review.SeverityHigh:   lipgloss.Color("196"),
+		review.SeverityMedium: lipgloss.Color("214"),
+		review.SeverityLow:    lipgloss.Color("33"),
+		review.SeverityInfo:   lipgloss.Color("244"),
+	}
+)
+
+// loadDiffCmd 在后台读取文件带上下文的暂存区 diff，完成后发送 diffLoadedMsg。
+func loadDiffCmd(file string) tea.Cmd {
+	return func() tea.Msg {
+		diff, err := gitops.GetFileStagedDiffWithContext(file, diffContextLines)
+		if err != nil {
+			return diffLoadedMsg{file: file, err: err}
+		}
+		return diffLoadedMsg{file: file, lines: parseDiffLines(diff)}
+	}
+}
+
+// parseDiffLines 把单个文件的 unified diff 解析为带新旧行号的行列表，跳过 hunk 之前的文件头。
+func parseDiffLines(diff string) []diffLine {
+	var (
+		lines    []diffLine
+		old, cur int
+		inHunk   bool
+	)
+
+	for _, raw := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(raw, "@@") {
+			// 形如 "@@ -a,b +c,d @@ func name"，只需要两侧的起始行号
+			if fields := strings.Fields(raw); len(fields) >= 3 {
+				old = hunkStart(fields[1])
+				cur = hunkStart(fields[2])
+			}
+			inHunk = true
+			lines = append(lines, diffLine{kind: '@', text: raw})
+			continue
+		}
+		if !inHunk || raw == "" || strings.HasPrefix(raw, `\`) {
+			continue
+		}
+
+		switch raw[0] {
+		case '+':
+			lines = append(lines, diffLine{kind: '+', newLine: cur, text: raw[1:]})
+			cur++
+		case '-':
+			lines = append(lines, diffLine{kind: '-', oldLine: old, text: raw[1:]})
+			old++
+		default:
+			lines = append(lines, diffLine{kind: ' ', oldLine: old, newLine: cur, text: raw[1:]})
+			old++
+			cur++
+		}
+	}
+
+	return lines
+}
+
+// hunkStart 解析 hunk 头中 "-a,b" 或 "+c,d" 的起始行号。
+func hunkStart(field string) int {
+	field = strings.TrimLeft(field, "+-")
+	if i := strings.IndexByte(field, ','); i >= 0 {
+		field = field[:i]
+	}
+	n, _ := strconv.Atoi(field)
+	return n
+}
+
+// codeHighlighter 使用 chroma 对单行代码做终端语法高亮。
+type codeHighlighter struct {
+	lexer     chroma.Lexer
+	formatter chroma.Formatter
+	style     *chroma.Style
+}
+
+// newCodeHighlighter 根据文件名选择 lexer，无法识别时退回纯文本。
+func newCodeHighlighter(file string) codeHighlighter {
+	lexer := lexers.Match(file)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	return codeHighlighter{
+		lexer:     chroma.Coalesce(lexer),
+		formatter: formatters.Get("terminal256"),
+		style:     styles.Get("monokai"),
+	}
+}
+
+// highlight 返回高亮后的单行代码，失败时返回原文。
+//
+// 逐行高亮无法感知跨行的结构（如多行注释、原始字符串），但 diff 本身就是片段，这种折中足够实用。
+func (h codeHighlighter) highlight(text string) string {
+	it, err := h.lexer.Tokenise(nil, text)
+	if err != nil {
+		return text
+	}
+
+	var buf bytes.Buffer
+	if err := h.formatter.Format(&buf, h.style, it); err != nil {
+		return text
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// renderDiff 渲染 diff 面板内容：左侧 gutter 为旧/新行号与问题标记，右侧为高亮后的代码。
+//
+// 有问题引用的新文件行在 gutter 中以按严重程度着色的 "●" 标出，当前选中问题所在行以 "▶" 标出。
+// 代码行不折行，超出宽度的部分被截断。
+func renderDiff(file string, lines []diffLine, findings []review.Finding, selected, width int) string {
+	marks := make(map[int]review.Severity)
+	for _, f := range findings {
+		if f.Line <= 0 {
+			continue
+		}
+		if cur, ok := marks[f.Line]; !ok || f.Severity.Rank() > cur.Rank() {
+			marks[f.Line] = f.Severity
+		}
+	}
+
+	selectedLine := 0
+	if selected >= 0 && selected < len(findings) {
+		selectedLine = findings[selected].Line
+	}
+
+	h := newCodeHighlighter(file)
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l.kind == '@' {
+			out = append(out, ansi.Truncate(hunkStyle.Render(l.text), width, "…"))
+			continue
+		}
+
+		marker := " "
+		if sev, ok := marks[l.newLine]; ok && l.newLine > 0 {
+			marker = lipgloss.NewStyle().Foreground(severityColors[sev]).Render("●")
+			if l.newLine == selectedLine {
+				marker = selectedMarkerStyle.Render("▶")
+			}
+		}
+
+		sign := " "
+		switch l.kind {
+		case '+':
+			sign = addSignStyle.Render("+")
+		case '-':
+			sign = delSignStyle.Render("-")
+		}
+
+		gutter := gutterStyle.Render(fmt.Sprintf("%4s %4s", lineNo(l.oldLine), lineNo(l.newLine)))
+		// 制表符在终端中的宽度不固定，会打乱截断与对齐，统一展开为 4 个空格
+		code := h.highlight(strings.ReplaceAll(l.text, "\t", "    "))
+		line := gutter + " " + marker + sign + " " + code
+		out = append(out, ansi.Truncate(line, width, "…"))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// refreshDiff 重新渲染当前文件（见 currentFile）的 diff 面板。diff 尚未读取时发起后台读取。
+func (m *Model) refreshDiff() tea.Cmd {
+	if !m.showDiff || m.selected < 0 || m.selected >= len(m.files) {
+		return nil
+	}
+
+	file := m.currentFile()
+	if file == "" {
+		m.diffView.SetContent("当前审查涉及多个文件，选中问题或文件后显示其所在文件的 diff。")
+		return nil
+	}
+	lines, ok := m.diffs[file]
+	if !ok {
+		m.diffView.SetContent("正在读取 diff...")
+		return loadDiffCmd(file)
+	}
+
+	var findings []review.Finding
+	selected := m.finding
+	if rev := m.currentReview(); rev != nil {
+		findings = rev.Findings
+		if len(rev.Files) > 0 {
+			// 整体审查与分组审查的问题分属不同文件，只标出当前 diff 所在文件中的问题
+			findings, selected = nil, -1
+			for i, f := range rev.Findings {
+				if f.File != file {
+					continue
+				}
+				if i == m.finding {
+					selected = len(findings)
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	m.diffView.SetContent(renderDiff(file, lines, findings, selected, m.diffView.Width))
+	return nil
+}
+
+// scrollDiffToFinding 滚动 diff 面板，使选中问题引用的行可见。
+func (m *Model) scrollDiffToFinding() {
+	f := m.currentFinding()
+	if !m.showDiff || f == nil || f.Line <= 0 {
+		return
+	}
+
+	idx := diffLineIndex(m.diffs[f.File], f.Line)
+	if idx < 0 {
+		return
+	}
+	top := m.diffView.YOffset
+	if idx < top || idx >= top+m.diffView.Height {
+		m.diffView.SetYOffset(idx - m.diffView.Height/3)
+	}
+}
+
+// lineNo 把行号格式化为字符串，0 表示该侧不存在对应行。
+func lineNo(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+// diffLineIndex 返回新文件第 line 行在 diff 行列表中的下标，找不到时返回 -1。
+func diffLineIndex(lines []diffLine, line int) int {
+	for i, l := range lines {
+		if l.newLine == line && l.kind != '-' {
+			return i
+		}
+	}
+	return -1
+}
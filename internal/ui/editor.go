@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// editorFinishedMsg 是外部编辑器退出、TUI 恢复后发送给 UI 的消息。
+//
+// - file: 被编辑的文件（相对仓库根目录）
+// - changed: 编辑前后工作区中的文件内容是否发生变化
+// - unstaged: 编辑之前就已存在的未暂存 hunk 的正文，重新审查时不暂存它们
+type editorFinishedMsg struct {
+	file     string
+	changed  bool
+	unstaged map[string]bool
+	err      error
+}
+
+// editorLineStyles 记录使用 "file:line" 而不是 "+line file" 定位行号的编辑器。
+//
+// - goto: 需要额外的 -g 参数（VS Code 系列）
+// - colon: 直接接受 file:line
+var editorLineStyles = map[string]string{
+	"code":          "goto",
+	"code-insiders": "goto",
+	"codium":        "goto",
+	"cursor":        "goto",
+	"subl":          "colon",
+	"sublime_text":  "colon",
+	"zed":           "colon",
+	"hx":            "colon",
+	"helix":         "colon",
+}
+
+// editorCommand 根据 $VISUAL / $EDITOR（都未设置时为 vi）构造在 line 行打开 path 的命令。
+//
+// 大多数终端编辑器（vim、nvim、nano、emacs、micro 等）使用 "+line file" 约定，
+// VS Code、Sublime Text、Helix 等使用 "file:line"。line <= 0 时只打开文件。
+// 编辑器变量中可以带参数（如 "code --wait"）。
+func editorCommand(path string, line int) (*exec.Cmd, error) {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	fields := strings.Fields(editor)
+	name := strings.TrimSuffix(filepath.Base(fields[0]), ".exe")
+	args := fields[1:]
+
+	switch {
+	case line <= 0:
+		args = append(args, path)
+	case editorLineStyles[name] == "goto":
+		args = append(args, "-g", fmt.Sprintf("%s:%d", path, line))
+	case editorLineStyles[name] == "colon":
+		args = append(args, fmt.Sprintf("%s:%d", path, line))
+	default:
+		args = append(args, fmt.Sprintf("+%d", line), path)
+	}
+
+	bin, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("找不到编辑器 %s：%w", fields[0], err)
+	}
+	return exec.Command(bin, args...), nil
+}
+
+// openEditorCmd 挂起 TUI，在外部编辑器中打开 file 的第 line 行；编辑器退出后 TUI 恢复，
+// 并通过比较编辑前后的文件内容判断文件是否被修改。
+func openEditorCmd(file string, line int) tea.Cmd {
+	root, err := gitops.GetRepoRoot()
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{file: file, err: err} }
+	}
+	path := filepath.Join(root, file)
+
+	cmd, err := editorCommand(path, line)
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{file: file, err: err} }
+	}
+
+	before := fileDigest(path)
+	unstaged := gitops.UnstagedHunkBodies(file)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{
+			file:     file,
+			changed:  fileDigest(path) != before,
+			unstaged: unstaged,
+			err:      err,
+		}
+	})
+}
+
+// fileDigest 返回文件内容的摘要，文件不存在或无法读取时返回空字符串。
+func fileDigest(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// openEditor 在编辑器中打开当前选中问题引用的位置；没有问题时打开当前文件。
+func (m *Model) openEditor() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.files) {
+		return nil
+	}
+
+	file, line := m.currentFile(), 0
+	if f := m.currentFinding(); f != nil {
+		// 没有注明文件的问题以整体审查或包作为 File，这时仍打开当前文件
+		if f.File != "" && f.File != review.OverallFile && len(m.members[f.File]) == 0 {
+			file = f.File
+		}
+		line = f.Line
+	}
+	if file == "" {
+		m.status = "当前审查涉及多个文件，请先选中一个文件或注明了文件的问题"
+		return nil
+	}
+	return openEditorCmd(file, line)
+}
+
+// handleEditorFinished 在编辑器退出后更新状态栏；文件有改动时提示是否重新审查。
+// 编辑之前就有未暂存的 hunk 时，提示中说明只会暂存编辑器中新做的修改。
+func (m *Model) handleEditorFinished(msg editorFinishedMsg) {
+	switch {
+	case msg.err != nil:
+		m.status = fmt.Sprintf("打开编辑器失败：%v", msg.err)
+	case msg.changed:
+		m.confirmReview, m.confirmUnstaged = msg.file, msg.unstaged
+		m.status = fmt.Sprintf("%s 已修改：按 y 暂存并重新审查，其他键忽略", msg.file)
+		if n := len(msg.unstaged); n > 0 {
+			m.status = fmt.Sprintf("%s 已修改：按 y 只暂存编辑器中的修改并重新审查（编辑前未暂存的 %d 个 hunk 保持不变），其他键忽略", msg.file, n)
+		}
+	default:
+		m.status = fmt.Sprintf("%s 未修改", msg.file)
+	}
+}
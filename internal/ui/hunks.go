@@ -0,0 +1,336 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// hunkAction 是对单个 hunk 的操作。
+type hunkAction int
+
+const (
+	hunkStage hunkAction = iota
+	hunkUnstage
+	hunkDiscard
+)
+
+// hunkBrowser 保存 hunk 面板的状态：选中文件已暂存与未暂存的 hunk，以及当前选中的 hunk。
+//
+// - cursor: 在"已暂存 hunk + 未暂存 hunk"合并列表中的下标
+// - confirmDiscard: 是否已按过一次 x，等待再次按 x 确认丢弃
+// - busy: 是否有 git apply 正在执行
+type hunkBrowser struct {
+	file     string
+	staged   *gitops.FileHunks
+	unstaged *gitops.FileHunks
+	loaded   bool
+	err      error
+
+	cursor         int
+	confirmDiscard bool
+	busy           bool
+
+	view viewport.Model
+}
+
+// hunksLoadedMsg 是读取文件 hunk 完成后发送给 UI 的消息。
+type hunksLoadedMsg struct {
+	file     string
+	staged   *gitops.FileHunks
+	unstaged *gitops.FileHunks
+	err      error
+}
+
+// hunkAppliedMsg 是对 hunk 执行暂存、撤回或丢弃完成后发送给 UI 的消息。
+type hunkAppliedMsg struct {
+	file   string
+	action hunkAction
+	err    error
+}
+
+var (
+	hunkTitleStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39"))
+
+	selectedHunkStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("229")).
+				Background(lipgloss.Color("57")).
+				Bold(true)
+
+	stagedTagStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("2"))
+
+	unstagedTagStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214"))
+)
+
+// loadHunksCmd 在后台读取 file 已暂存与未暂存的 hunk，完成后发送 hunksLoadedMsg。
+func loadHunksCmd(file string) tea.Cmd {
+	return func() tea.Msg {
+		staged, err := gitops.GetFileHunks(file, true)
+		if err != nil {
+			return hunksLoadedMsg{file: file, err: err}
+		}
+		unstaged, err := gitops.GetFileHunks(file, false)
+		if err != nil {
+			return hunksLoadedMsg{file: file, err: err}
+		}
+		return hunksLoadedMsg{file: file, staged: staged, unstaged: unstaged}
+	}
+}
+
+// applyHunkCmd 在后台对 fh 中的第 idx 个 hunk 执行 action，完成后发送 hunkAppliedMsg。
+func applyHunkCmd(fh *gitops.FileHunks, idx int, action hunkAction) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch action {
+		case hunkStage:
+			err = gitops.StageHunk(fh, idx)
+		case hunkUnstage:
+			err = gitops.UnstageHunk(fh, idx)
+		case hunkDiscard:
+			err = gitops.DiscardHunk(fh, idx)
+		}
+		return hunkAppliedMsg{file: fh.File, action: action, err: err}
+	}
+}
+
+// String 返回操作的中文名称，用于状态栏。
+func (a hunkAction) String() string {
+	switch a {
+	case hunkStage:
+		return "暂存"
+	case hunkUnstage:
+		return "撤回暂存"
+	default:
+		return "丢弃"
+	}
+}
+
+// entry 把合并列表中的下标 i 映射为具体的 hunk 集合与其中的下标。已暂存的 hunk 排在前面。
+func (b *hunkBrowser) entry(i int) (*gitops.FileHunks, int) {
+	if b.staged != nil && i < len(b.staged.Hunks) {
+		return b.staged, i
+	}
+	if b.staged != nil {
+		i -= len(b.staged.Hunks)
+	}
+	if b.unstaged != nil && i < len(b.unstaged.Hunks) {
+		return b.unstaged, i
+	}
+	return nil, 0
+}
+
+// count 返回 hunk 总数。
+func (b *hunkBrowser) count() int {
+	n := 0
+	if b.staged != nil {
+		n += len(b.staged.Hunks)
+	}
+	if b.unstaged != nil {
+		n += len(b.unstaged.Hunks)
+	}
+	return n
+}
+
+// openHunks 为当前选中文件（见 currentFile）打开 hunk 面板。
+func (m *Model) openHunks() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.files) {
+		return nil
+	}
+
+	file := m.currentFile()
+	if file == "" {
+		m.status = "整体审查涉及多个文件，请先选中一个注明了文件的问题"
+		return nil
+	}
+	m.hunks = &hunkBrowser{file: file, view: viewport.New(0, 0)}
+	return loadHunksCmd(file)
+}
+
+// updateHunks 处理 hunk 面板打开时的按键：
+// ↑/↓ 选择 hunk，s 暂存，u 撤回暂存，x 丢弃（需再按一次确认），Esc 或 h 关闭，其余交给 viewport 滚动。
+func (m Model) updateHunks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.hunks
+	discard := b.confirmDiscard
+	b.confirmDiscard = false
+
+	switch msg.String() {
+	case "esc", "h":
+		m.hunks = nil
+		return m, nil
+
+	case "up", "k":
+		if b.cursor > 0 {
+			b.cursor--
+		}
+		m.refreshHunks()
+		return m, nil
+
+	case "down", "j":
+		if b.cursor < b.count()-1 {
+			b.cursor++
+		}
+		m.refreshHunks()
+		return m, nil
+
+	case "s", "u", "x":
+		fh, idx := b.entry(b.cursor)
+		if fh == nil || b.busy {
+			return m, nil
+		}
+
+		var action hunkAction
+		switch {
+		case msg.String() == "s" && !fh.Staged:
+			action = hunkStage
+		case msg.String() == "u" && fh.Staged:
+			action = hunkUnstage
+		case msg.String() == "x" && !fh.Staged:
+			if !discard {
+				b.confirmDiscard = true
+				m.status = "再按一次 x 丢弃该 hunk 在工作区中的修改（不可撤销）"
+				return m, nil
+			}
+			action = hunkDiscard
+		case fh.Staged:
+			m.status = "该 hunk 已暂存：按 u 撤回暂存"
+			return m, nil
+		default:
+			m.status = "该 hunk 尚未暂存：按 s 暂存，按 x 丢弃"
+			return m, nil
+		}
+
+		b.busy = true
+		return m, applyHunkCmd(fh, idx, action)
+	}
+
+	var cmd tea.Cmd
+	b.view, cmd = b.view.Update(msg)
+	return m, cmd
+}
+
+// handleHunksLoaded 更新 hunk 面板的内容。hunk 数量减少时保持选中位置不越界。
+func (m *Model) handleHunksLoaded(msg hunksLoadedMsg) {
+	b := m.hunks
+	if b == nil || b.file != msg.file {
+		return
+	}
+
+	b.loaded = true
+	b.err = msg.err
+	b.staged = msg.staged
+	b.unstaged = msg.unstaged
+	if n := b.count(); b.cursor >= n {
+		b.cursor = n - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	m.refreshHunks()
+}
+
+// handleHunkApplied 在 hunk 操作完成后重新读取 hunk，并刷新文件列表以重新审查受影响的文件。
+func (m *Model) handleHunkApplied(msg hunkAppliedMsg) tea.Cmd {
+	if m.hunks != nil {
+		m.hunks.busy = false
+	}
+	if msg.err != nil {
+		m.status = fmt.Sprintf("%s hunk 失败：%v", msg.action, msg.err)
+		return nil
+	}
+
+	cmd := m.refreshFiles("", nil)
+	m.status = fmt.Sprintf("已%s %s 中的 hunk，正在刷新审查...", msg.action, msg.file)
+	if m.hunks == nil {
+		return cmd
+	}
+	return tea.Batch(loadHunksCmd(m.hunks.file), cmd)
+}
+
+// refreshHunks 重新渲染 hunk 面板，并滚动使选中的 hunk 可见。
+func (m *Model) refreshHunks() {
+	b := m.hunks
+	if b == nil {
+		return
+	}
+
+	_, _, rightWidth, bodyHeight := m.layout()
+	b.view.Width = rightWidth
+	b.view.Height = bodyHeight - 2
+
+	switch {
+	case b.err != nil:
+		b.view.SetContent(fmt.Sprintf("读取 hunk 失败：%v", b.err))
+		return
+	case !b.loaded:
+		b.view.SetContent("正在读取 hunk...")
+		return
+	case b.count() == 0:
+		b.view.SetContent("该文件没有可操作的 hunk。")
+		return
+	}
+
+	h := newCodeHighlighter(b.file)
+	var (
+		out      []string
+		selected int
+	)
+	for i := 0; i < b.count(); i++ {
+		fh, idx := b.entry(i)
+		hunk := fh.Hunks[idx]
+		added, removed := hunk.Stats()
+
+		tag := unstagedTagStyle.Render("[未暂存]")
+		if fh.Staged {
+			tag = stagedTagStyle.Render("[已暂存]")
+		}
+		title := fmt.Sprintf("%s %s  +%d -%d", tag, hunkTitleStyle.Render(hunk.Header), added, removed)
+		if i == b.cursor {
+			selected = len(out)
+			title = selectedHunkStyle.Render("▶") + " " + title
+		} else {
+			title = "  " + title
+		}
+		out = append(out, ansi.Truncate(title, b.view.Width, "…"))
+
+		for _, l := range hunk.Lines {
+			if l == "" {
+				continue
+			}
+			sign := " "
+			switch l[0] {
+			case '+':
+				sign = addSignStyle.Render("+")
+			case '-':
+				sign = delSignStyle.Render("-")
+			case '\\':
+				out = append(out, gutterStyle.Render("    "+l))
+				continue
+			}
+			code := h.highlight(strings.ReplaceAll(l[1:], "\t", "    "))
+			out = append(out, ansi.Truncate("  "+sign+" "+code, b.view.Width, "…"))
+		}
+		out = append(out, "")
+	}
+
+	b.view.SetContent(strings.Join(out, "\n"))
+	if top := b.view.YOffset; selected < top || selected >= top+b.view.Height {
+		b.view.SetYOffset(selected)
+	}
+}
+
+// viewHunks 渲染 hunk 面板：hunk 列表在上，操作提示在下。
+func (m Model) viewHunks() string {
+	b := m.hunks
+	title := fmt.Sprintf("Hunk：%s", b.file)
+	hint := chatHintStyle.Render("↑/↓ 选择  s 暂存  u 撤回暂存  x 丢弃  Esc 返回审查报告")
+	return lipgloss.JoinVertical(lipgloss.Left, hunkTitleStyle.Render(title), b.view.View(), hint)
+}
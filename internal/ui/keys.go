@@ -0,0 +1,174 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap 汇总 TUI 中的全部快捷键，同时实现 help.KeyMap，供 "?" 帮助面板展示。
+type keyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Fold     key.Binding
+	Tab      key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	HalfUp   key.Binding
+	HalfDown key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+
+	Search    key.Binding
+	NextMatch key.Binding
+	PrevMatch key.Binding
+
+	NextFinding   key.Binding
+	PrevFinding   key.Binding
+	Patch         key.Binding
+	ApplyWorktree key.Binding
+	ApplyIndex    key.Binding
+	Chat          key.Binding
+	Diff          key.Binding
+	Edit          key.Binding
+	Rereview      key.Binding
+	Refresh       key.Binding
+	Hunks         key.Binding
+	Mark          key.Binding
+	Commit        key.Binding
+
+	Help key.Binding
+	Quit key.Binding
+}
+
+// defaultKeyMap 返回默认的快捷键绑定。
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "上移"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "下移"),
+		),
+		Fold: key.NewBinding(
+			key.WithKeys("enter", "left", "right"),
+			key.WithHelp("enter/←/→", "折叠/展开包"),
+		),
+		Tab: key.NewBinding(
+			key.WithKeys("tab", "shift+tab"),
+			key.WithHelp("tab", "切换焦点"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup", "b"),
+			key.WithHelp("pgup/b", "上翻页"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", "f", " "),
+			key.WithHelp("pgdn/f", "下翻页"),
+		),
+		HalfUp: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "上翻半页"),
+		),
+		HalfDown: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+			key.WithHelp("ctrl+d", "下翻半页"),
+		),
+		Top: key.NewBinding(
+			key.WithKeys("g", "home"),
+			key.WithHelp("g", "跳到开头"),
+		),
+		Bottom: key.NewBinding(
+			key.WithKeys("G", "end"),
+			key.WithHelp("G", "跳到末尾"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "搜索审查内容"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "下一个匹配"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "上一个匹配"),
+		),
+		NextFinding: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "下一个问题"),
+		),
+		PrevFinding: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "上一个问题"),
+		),
+		Patch: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "预览补丁"),
+		),
+		ApplyWorktree: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "补丁应用到工作区"),
+		),
+		ApplyIndex: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", "补丁应用到暂存区"),
+		),
+		Chat: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "追问"),
+		),
+		Diff: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "显示/隐藏 diff"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "在编辑器中打开"),
+		),
+		Rereview: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "重新审查当前文件"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "刷新并审查有变化的文件"),
+		),
+		Hunks: key.NewBinding(
+			key.WithKeys("h"),
+			key.WithHelp("h", "按 hunk 暂存/撤回/丢弃"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "标记问题（接受/忽略/不修复）"),
+		),
+		Commit: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "生成提交信息，编辑后提交"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "帮助"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q", "ctrl+c"),
+			key.WithHelp("q", "退出"),
+		),
+	}
+}
+
+// ShortHelp 返回状态栏中展示的常用快捷键。
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Tab, k.Search, k.NextFinding, k.Diff, k.Edit, k.Chat, k.Help, k.Quit}
+}
+
+// FullHelp 返回帮助面板中按列分组展示的全部快捷键。
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Fold, k.Tab, k.PageUp, k.PageDown, k.HalfUp, k.HalfDown, k.Top, k.Bottom},
+		{k.Search, k.NextMatch, k.PrevMatch},
+		{k.NextFinding, k.PrevFinding, k.Diff, k.Patch, k.ApplyWorktree, k.ApplyIndex, k.Mark, k.Edit, k.Chat},
+		{k.Rereview, k.Refresh, k.Hunks, k.Commit},
+		{k.Help, k.Quit},
+	}
+}
@@ -1,44 +1,141 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 
-	"github.com/GuLuGuLuGit/review-go/internal/ai"
 	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+	"github.com/GuLuGuLuGit/review-go/internal/policy"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
 )
 
 // reviewLoadedMsg 是后台审核任务完成后发送给 UI 的消息。
+//
+// 单个文件审查失败时记录在 failed 中，可在界面中按 r 原地重试；err 只表示整体流程失败（如读取暂存区出错）。
+// scores 与 skipped 来自审查前的风险评分（见 review.Runner.Triage），files 已按评分从高到低排列。
+// 分组审查时 files 为各审查单元的 key，members 为每个单元包含的文件。
 type reviewLoadedMsg struct {
 	files   []string
-	reviews map[string]string
+	members map[string][]string
+	reviews map[string]*review.FileReview
+	failed  map[string]error
+	scores  map[string]review.RiskScore
+	skipped []string
 	err     error
 }
 
+// patchAppliedMsg 是应用补丁的后台任务完成后发送给 UI 的消息。
+//
+// - rev / idx: 补丁所属的审查结果与问题下标
+// - checks: 应用成功后对同一审查结果中其余补丁重新校验的结果（问题下标 → 失败原因，通过时为空字符串）
+type patchAppliedMsg struct {
+	key    string
+	rev    *review.FileReview
+	idx    int
+	cached bool
+	err    error
+	stale  bool
+	checks map[int]string
+}
+
 // Model 是 Bubble Tea 的主状态机。
 //
-// - files: 暂存区中有变更的文件列表
-// - reviews: 每个文件对应的 LLM 审查结果（Markdown）
+// - files: 暂存区中有变更的文件列表（分组审查时为各审查单元的 key，见 review.Unit）
+// - reviews: 每个文件对应的审查结果（LLM 报告 + 结构化问题）
 // - loading: 是否处于加载状态（调用 Git + AI 中）
 // - selected: 当前选中的文件索引
 // - err: 加载过程中的错误（如果有）
-// - provider: 用于实际调用 LLM 的接口实现
+// - runner: 串联 Git、静态分析与 LLM 调用的审查流程
+// - finding: 当前文件中选中的问题下标
+// - showPatch: 是否在右侧预览选中问题的补丁
+// - applied: 已应用过的补丁（key 为 findingKey），避免重复应用
+// - status: 底部状态栏中展示的最近一次操作结果
+// - chatOpen / chats / chatInput: 追问面板的开关、按文件保存的对话以及输入框
+// - focus: 当前接收方向键的面板（文件列表或审查内容）
+// - viewport / reviewLines: 可滚动的审查内容及其去掉样式后的纯文本行（用于搜索与定位）
+// - searching / search / query / matches / match: "/" 搜索的输入状态与匹配结果
+// - keys / help / showHelp: 快捷键绑定与 "?" 帮助面板
+// - showDiff / diffs / diffView: diff 面板的开关、按文件缓存的带上下文 diff 以及其 viewport
+// - confirmReview / confirmUnstaged: 在编辑器中修改后等待确认重新审查的文件（为空表示没有待确认的提示），
+//   以及编辑前该文件中未暂存的 hunk（确认后不暂存它们）
+// - failed / reviewing: 审查失败的文件及其错误、正在重新审查中的文件
+// - hunks: 打开的 hunk 面板（暂存/撤回/丢弃单个 hunk），为 nil 表示未打开
+// - marking / reasoning / reasonInput: 标记问题（接受/忽略/不修复）的提示与忽略原因输入框
+// - committing: 是否正在生成提交信息或等待 git commit 结束
+// - overallPending: 有文件的 diff 变化后整体审查已过期，等正在进行的文件审查全部结束后重新发起
+// - scores / skipped: 各文件审查前的风险评分，以及因 --max-files 限制而没有审查的文件
+// - members / collapsed / child: 分组审查时各单元包含的文件、折叠起来的单元，以及在当前单元下选中的文件（为空表示单元本身）
 type Model struct {
 	files    []string
-	reviews  map[string]string
+	reviews  map[string]*review.FileReview
 	loading  bool
 	selected int
 
-	spinner  spinner.Model
-	width    int
-	height   int
-	provider ai.LLMProvider
+	finding   int
+	showPatch bool
+	applied   map[string]bool
+	status    string
+
+	chatOpen  bool
+	chats     map[string]*chatSession
+	chatInput textinput.Model
+
+	focus       focusArea
+	viewport    viewport.Model
+	reviewLines []string
+
+	searching bool
+	search    textinput.Model
+	query     string
+	matches   []int
+	match     int
+
+	keys     keyMap
+	help     help.Model
+	showHelp bool
+
+	showDiff bool
+	diffs    map[string][]diffLine
+	diffView viewport.Model
+
+	confirmReview   string
+	confirmUnstaged map[string]bool
+
+	failed    map[string]error
+	reviewing map[string]bool
+
+	hunks *hunkBrowser
+
+	marking     bool
+	reasoning   bool
+	reasonInput textinput.Model
+
+	committing bool
+
+	overallPending bool
+
+	scores  map[string]review.RiskScore
+	skipped []string
+
+	members   map[string][]string
+	collapsed map[string]bool
+	child     string
+
+	spinner spinner.Model
+	width   int
+	height  int
+	runner  *review.Runner
 
 	err error
 }
@@ -72,23 +169,50 @@ var (
 
 	reviewStyle = lipgloss.NewStyle().
 		Padding(0, 1)
+
+	helpStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2)
 )
 
 // NewModel 创建一个带有初始 loading 状态和 Spinner 的 Model。
-// 通过依赖注入的方式传入一个实现了 LLMProvider 接口的实例，
+// 通过依赖注入的方式传入审查流程 Runner（其内部持有 LLMProvider），
 // 方便后续在不同 AI 提供商之间切换。
-func NewModel(provider ai.LLMProvider) Model {
+func NewModel(runner *review.Runner) Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = spinnerStyle
 
 	return Model{
 		files:    nil,
-		reviews:  make(map[string]string),
+		reviews:  make(map[string]*review.FileReview),
 		loading:  true,
 		selected: 0,
+		applied:  make(map[string]bool),
 		spinner:  s,
-		provider: provider,
+		runner:   runner,
+
+		chats:     make(map[string]*chatSession),
+		chatInput: newChatInput(),
+
+		focus:    focusFiles,
+		viewport: viewport.New(0, 0),
+		search:   newSearchInput(),
+		match:    -1,
+		keys:     defaultKeyMap(),
+		help:     help.New(),
+
+		diffs:    make(map[string][]diffLine),
+		diffView: viewport.New(0, 0),
+
+		failed:    make(map[string]error),
+		reviewing: make(map[string]bool),
+		scores:    make(map[string]review.RiskScore),
+		members:   make(map[string][]string),
+		collapsed: make(map[string]bool),
+
+		reasonInput: newReasonInput(),
 	}
 }
 
@@ -98,83 +222,198 @@ func NewModel(provider ai.LLMProvider) Model {
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
-		loadReviewsCmd(m.provider),
+		loadReviewsCmd(m.runner),
 	)
 }
 
-// loadReviewsCmd 在后台执行 Git + AI 审核逻辑，完成后发送 reviewLoadedMsg。
-func loadReviewsCmd(provider ai.LLMProvider) tea.Cmd {
+// loadReviewsCmd 在后台执行 Git + 静态分析 + AI 审核逻辑，完成后发送 reviewLoadedMsg。
+func loadReviewsCmd(runner *review.Runner) tea.Cmd {
 	return func() tea.Msg {
-		if provider == nil {
-			return reviewLoadedMsg{err: fmt.Errorf("LLM Provider 未初始化")}
+		if runner == nil {
+			return reviewLoadedMsg{err: fmt.Errorf("审查流程未初始化")}
 		}
 
-		files, err := gitops.GetChangedFiles()
+		files, err := runner.ChangedFiles()
 		if err != nil {
 			return reviewLoadedMsg{err: fmt.Errorf("获取暂存区文件失败：%w", err)}
 		}
 
-		if len(files) == 0 {
+		depUnits := runner.DepsUnits()
+		if len(files) == 0 && len(depUnits) == 0 {
 			return reviewLoadedMsg{
 				files:   []string{},
-				reviews: map[string]string{},
+				reviews: map[string]*review.FileReview{},
 				err:     nil,
 			}
 		}
 
-		reviews := make(map[string]string, len(files))
-		for _, f := range files {
-			diff, err := getFileStagedDiff(f)
+		// 按风险评分排序，设置了 --max-files 时只审查评分最高的几个文件
+		triage := runner.Triage(files)
+		files = triage.Files
+
+		// 先对所有变更包统一跑一遍静态分析，避免按文件重复执行 go vet。
+		diags := runner.Analyze(files)
+
+		// 按 --group 划分审查单元：默认每个文件一个单元，分组时同一个包（模块）中的文件合在一起审查；
+		// go.mod / go.sum 有变化的模块排在最前面
+		units := append(depUnits, runner.Units(files)...)
+		keys := make([]string, 0, len(units))
+		members := make(map[string][]string)
+		reviews := make(map[string]*review.FileReview, len(units))
+		failed := make(map[string]error)
+		for _, u := range units {
+			keys = append(keys, u.Key)
+			if runner.Grouped() && !review.IsModFile(u.Key) {
+				members[u.Key] = u.Files
+			}
+			rev, err := runner.ReviewUnit(u, diags, false)
 			if err != nil {
-				return reviewLoadedMsg{
-					err: fmt.Errorf("获取文件 %s 的 diff 失败：%w", f, err),
-				}
+				failed[u.Key] = err
 			}
+			// 失败时仍可能带回不依赖 LLM 的结果（例如依赖检查），与错误一起展示
+			if rev != nil {
+				reviews[u.Key] = rev
+			}
+		}
 
-			// 组合审查提示词，将原先 Reviewer 中的系统提示融合到单条 prompt 中，
-			// 通过 LLMProvider 的 Chat 方法调用。
-			reviewPrompt := buildReviewPrompt(diff)
-
-			reply, err := provider.Chat(reviewPrompt)
+		// 逐个审查之后再整体审查一次，结果作为第一项排在列表顶部
+		if runner.WantsOverall(keys) {
+			rev, err := runner.ReviewOverall(reviews, false)
 			if err != nil {
-				return reviewLoadedMsg{
-					err: fmt.Errorf("审查文件 %s 失败：%w", f, err),
-				}
+				failed[review.OverallFile] = err
+			} else {
+				reviews[review.OverallFile] = rev
 			}
+			keys = append([]string{review.OverallFile}, keys...)
+		}
 
-			reviews[f] = reply
+		// 按 --max-files 跳过的文件不发送给 LLM，但其中导出 API 的不兼容变化照常列出
+		for _, rev := range runner.SkippedReviews(triage.Skipped) {
+			keys = append(keys, rev.File)
+			reviews[rev.File] = rev
 		}
 
 		return reviewLoadedMsg{
-			files:   files,
+			files:   keys,
+			members: members,
 			reviews: reviews,
+			failed:  failed,
+			scores:  triage.Scores,
+			skipped: triage.Skipped,
 			err:     nil,
 		}
 	}
 }
 
-// getFileStagedDiff 获取单个文件在暂存区中的 diff（仅该文件），等价于：
-// git diff --cached --unified=0 -- <file>
-func getFileStagedDiff(file string) (string, error) {
-	cmd := exec.Command("git", "diff", "--cached", "--unified=0", "--", file)
-	out, err := cmd.CombinedOutput()
-	output := strings.TrimSpace(string(out))
+// applyPatchCmd 在后台把 rev 中第 idx 个问题的补丁应用到工作区或暂存区（cached 为 true），完成后发送 patchAppliedMsg。
+//
+// 审查时的校验只针对当时的暂存区，此后可能已经应用了其他补丁或修改了文件，因此应用前针对实际目标重新校验；
+// 应用成功后再针对同一目标重新校验 pending 中其余尚未应用的补丁（问题下标 → 补丁）。
+func applyPatchCmd(key string, rev *review.FileReview, idx int, cached bool, pending map[int]string) tea.Cmd {
+	patch := rev.Findings[idx].Patch
+	return func() tea.Msg {
+		msg := patchAppliedMsg{key: key, rev: rev, idx: idx, cached: cached}
+		if err := gitops.CheckPatch(patch, cached); err != nil {
+			msg.err, msg.stale = err, true
+			return msg
+		}
+		if msg.err = gitops.ApplyPatch(patch, cached); msg.err != nil {
+			return msg
+		}
 
-	if err != nil {
-		if strings.Contains(output, "not a git repository") {
-			return "", fmt.Errorf("当前目录不是 git 仓库：%s", output)
+		msg.checks = make(map[int]string, len(pending))
+		for i, p := range pending {
+			msg.checks[i] = ""
+			if err := gitops.CheckPatch(p, cached); err != nil {
+				msg.checks[i] = err.Error()
+			}
 		}
-		if output != "" {
-			return "", fmt.Errorf("执行 git diff 失败：%s", output)
+		return msg
+	}
+}
+
+// findingKey 返回用于标识某个文件中第 idx 个问题的 key。
+func findingKey(file string, idx int) string {
+	return fmt.Sprintf("%s#%d", file, idx)
+}
+
+// skippedStatus 返回因 --max-files 限制跳过了部分文件时的状态栏提示。
+func (m Model) skippedStatus() string {
+	return fmt.Sprintf("按风险评分跳过了 %d 个文件（--max-files）", len(m.skipped))
+}
+
+// currentReview 返回当前选中文件的审查结果，未选中或尚无结果时返回 nil。
+func (m Model) currentReview() *review.FileReview {
+	if m.selected < 0 || m.selected >= len(m.files) {
+		return nil
+	}
+	return m.reviews[m.files[m.selected]]
+}
+
+// currentFinding 返回当前选中的问题，没有时返回 nil。
+func (m Model) currentFinding() *review.Finding {
+	rev := m.currentReview()
+	if rev == nil || m.finding < 0 || m.finding >= len(rev.Findings) {
+		return nil
+	}
+	return &rev.Findings[m.finding]
+}
+
+// selectFile 切换到第 idx 个文件，重置与问题相关的选择状态并回到审查内容与 diff 的顶部。
+func (m *Model) selectFile(idx int) tea.Cmd {
+	m.selected = idx
+	m.child = ""
+	m.finding = 0
+	m.showPatch = false
+	m.refreshReview()
+	m.viewport.GotoTop()
+	m.diffView.GotoTop()
+	return m.refreshDiff()
+}
+
+// selectFinding 选中当前文件的第 idx 个问题，并让审查内容与 diff 面板都滚动到该问题。
+// 整体审查与分组审查中的问题可能位于另一个文件，此时返回读取该文件 diff 的命令；
+// 分组审查时左侧列表同步选中问题所在的文件（所在的包折叠时除外）。
+func (m *Model) selectFinding(idx int) tea.Cmd {
+	m.finding = idx
+	if f := m.currentFinding(); f != nil && !m.collapsed[m.files[m.selected]] {
+		for _, member := range m.members[m.files[m.selected]] {
+			if member == f.File {
+				m.child = f.File
+			}
 		}
-		return "", fmt.Errorf("执行 git diff 失败：%w", err)
+	}
+	m.refreshReview()
+	m.scrollToFinding()
+	cmd := m.refreshDiff()
+	m.scrollDiffToFinding()
+	return cmd
+}
+
+// applyCurrentPatch 校验并应用当前选中问题的补丁。
+func (m *Model) applyCurrentPatch(cached bool) tea.Cmd {
+	f := m.currentFinding()
+	switch {
+	case f == nil || f.Patch == "":
+		m.status = "当前问题没有可应用的补丁"
+		return nil
 	}
 
-	if output == "" {
-		return "", fmt.Errorf("文件 %s 在暂存区没有 diff 输出", file)
+	file := m.files[m.selected]
+	key := findingKey(file, m.finding)
+	if m.applied[key] {
+		m.status = "该补丁已经应用过"
+		return nil
 	}
 
-	return output, nil
+	rev := m.currentReview()
+	pending := make(map[int]string)
+	for i, other := range rev.Findings {
+		if i != m.finding && other.Patch != "" && !m.applied[findingKey(file, i)] {
+			pending[i] = other.Patch
+		}
+	}
+	return applyPatchCmd(key, rev, m.finding, cached, pending)
 }
 
 // Update 处理所有消息（键盘事件、窗口大小变化、后台任务结果等）。
@@ -183,7 +422,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		return m, nil
+		m.help.Width = msg.Width
+		return m, m.resizeViewport()
+
+	case tea.MouseMsg:
+		// 鼠标滚轮滚动指针所在的面板：diff 面板或审查内容
+		leftWidth, diffWidth, _, _ := m.layout()
+		var cmd tea.Cmd
+		if m.showDiff && msg.X >= leftWidth+2 && msg.X < leftWidth+2+diffWidth {
+			m.diffView, cmd = m.diffView.Update(msg)
+		} else {
+			m.viewport, cmd = m.viewport.Update(msg)
+		}
+		return m, cmd
+
+	case diffLoadedMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("读取 %s 的 diff 失败：%v", msg.file, msg.err)
+			return m, nil
+		}
+		m.diffs[msg.file] = msg.lines
+		cmd := m.refreshDiff()
+		m.scrollDiffToFinding()
+		return m, cmd
 
 	case spinner.TickMsg:
 		if m.loading {
@@ -199,35 +460,263 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if msg.err == nil {
 			m.files = msg.files
+			m.members = msg.members
 			m.reviews = msg.reviews
+			m.failed = msg.failed
+			m.scores = msg.scores
+			m.skipped = msg.skipped
+			denied := 0
+			for _, err := range m.failed {
+				if errors.Is(err, policy.ErrDenied) {
+					denied++
+				}
+			}
+			switch {
+			case len(m.failed) > denied:
+				m.status = fmt.Sprintf("%d 个文件审查失败，选中后按 r 重试", len(m.failed)-denied)
+			case denied > 0:
+				m.status = fmt.Sprintf("%d 个文件按外发策略未发送给 LLM", denied)
+			case len(m.skipped) > 0:
+				m.status = m.skippedStatus()
+			}
 			if len(m.files) > 0 && m.selected >= len(m.files) {
 				m.selected = 0
 			}
+			m.refreshReview()
+			return m, m.refreshDiff()
+		}
+		return m, nil
+
+	case patchAppliedMsg:
+		target := "工作区"
+		if msg.cached {
+			target = "暂存区"
+		}
+		switch {
+		case msg.stale:
+			// 重新校验失败时没有做任何修改，把失败原因记录下来，补丁预览中会展示
+			msg.rev.Findings[msg.idx].PatchErr = msg.err.Error()
+			m.status = fmt.Sprintf("补丁已无法干净地应用到%s（git apply --check 失败），未做任何修改", target)
+			m.refreshReview()
+			return m, nil
+		case msg.err != nil:
+			m.status = fmt.Sprintf("应用补丁到%s失败：%v", target, msg.err)
+			return m, nil
+		}
+		m.applied[msg.key] = true
+		m.status = fmt.Sprintf("已将补丁应用到%s", target)
+		stale := 0
+		for i, errText := range msg.checks {
+			msg.rev.Findings[i].PatchErr = errText
+			if errText != "" {
+				stale++
+			}
+		}
+		if stale > 0 {
+			m.status += fmt.Sprintf("，同一文件中另有 %d 个补丁已无法干净地应用", stale)
+		}
+		m.refreshReview()
+		return m, nil
+
+	case editorFinishedMsg:
+		m.handleEditorFinished(msg)
+		return m, nil
+
+	case fileReviewedMsg:
+		return m, m.handleFileReviewed(msg)
+
+	case filesRefreshedMsg:
+		return m, m.handleFilesRefreshed(msg)
+
+	case hunksLoadedMsg:
+		m.handleHunksLoaded(msg)
+		return m, nil
+
+	case hunkAppliedMsg:
+		return m, m.handleHunkApplied(msg)
+
+	case chatReplyMsg:
+		m.handleChatReply(msg)
+		return m, nil
+
+	case commitMsgGeneratedMsg:
+		return m, m.handleCommitMsgGenerated(msg)
+
+	case committedMsg:
+		return m, m.handleCommitted(msg)
+
+	case transcriptSavedMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("保存对话记录失败：%v", msg.err)
+		} else {
+			m.status = fmt.Sprintf("审查与对话记录已保存到 %s", msg.path)
 		}
 		return m, nil
 
 	case tea.KeyMsg:
-		// 全局退出快捷键
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
 		}
 
-		if m.loading || m.err != nil {
-			// 加载中或出错时，不处理上下选择
+		// 帮助面板打开时，任意键关闭
+		if m.showHelp {
+			m.showHelp = false
 			return m, nil
 		}
 
-		switch msg.String() {
-		case "up", "k":
-			if m.selected > 0 {
-				m.selected--
+		// 追问面板或搜索框打开时，按键优先交给输入框（包括 q）
+		if m.chatOpen {
+			return m.updateChat(msg)
+		}
+		if m.hunks != nil {
+			return m.updateHunks(msg)
+		}
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if m.reasoning {
+			return m.updateReason(msg)
+		}
+		if m.marking {
+			return m.updateMarking(msg)
+		}
+
+		// 编辑器修改文件后的确认提示：y 暂存并重新审查，其他键忽略
+		if file := m.confirmReview; file != "" {
+			m.confirmReview = ""
+			if msg.String() != "y" {
+				m.status = ""
+				return m, nil
 			}
-		case "down", "j":
-			if m.selected < len(m.files)-1 {
-				m.selected++
+			return m, m.refreshFiles(file, m.confirmUnstaged)
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+		}
+
+		if m.loading || m.err != nil {
+			// 加载中或出错时，不处理其他快捷键
+			return m, nil
+		}
+
+		return m.updateKeys(msg)
+	}
+
+	// 其他消息（如输入框光标闪烁）交给当前打开的输入框处理
+	var cmd tea.Cmd
+	switch {
+	case m.chatOpen:
+		m.chatInput, cmd = m.chatInput.Update(msg)
+	case m.searching:
+		m.search, cmd = m.search.Update(msg)
+	case m.reasoning:
+		m.reasonInput, cmd = m.reasonInput.Update(msg)
+	}
+	return m, cmd
+}
+
+// updateKeys 处理审查结果展示阶段的快捷键。
+func (m Model) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Tab):
+		// 焦点按 文件列表 → diff（显示时）→ 审查内容 循环
+		switch m.focus {
+		case focusFiles:
+			m.focus = focusReview
+			if m.showDiff {
+				m.focus = focusDiff
 			}
+		case focusDiff:
+			m.focus = focusReview
+		default:
+			m.focus = focusFiles
 		}
+
+	case key.Matches(msg, m.keys.Up):
+		if m.focus != focusFiles {
+			m.activeViewport().ScrollUp(1)
+		} else {
+			return m, m.moveSelection(-1)
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.focus != focusFiles {
+			m.activeViewport().ScrollDown(1)
+		} else {
+			return m, m.moveSelection(1)
+		}
+	case key.Matches(msg, m.keys.Fold):
+		if m.focus == focusFiles {
+			return m, m.toggleFold(msg.String())
+		}
+
+	case key.Matches(msg, m.keys.PageUp):
+		m.activeViewport().PageUp()
+	case key.Matches(msg, m.keys.PageDown):
+		m.activeViewport().PageDown()
+	case key.Matches(msg, m.keys.HalfUp):
+		m.activeViewport().HalfPageUp()
+	case key.Matches(msg, m.keys.HalfDown):
+		m.activeViewport().HalfPageDown()
+	case key.Matches(msg, m.keys.Top):
+		m.activeViewport().GotoTop()
+	case key.Matches(msg, m.keys.Bottom):
+		m.activeViewport().GotoBottom()
+
+	case key.Matches(msg, m.keys.Search):
+		m.searching = true
+		m.search.Reset()
+		return m, tea.Batch(m.resizeViewport(), m.search.Focus())
+	case key.Matches(msg, m.keys.NextMatch):
+		m.jumpMatch(1)
+	case key.Matches(msg, m.keys.PrevMatch):
+		m.jumpMatch(-1)
+
+	case key.Matches(msg, m.keys.NextFinding):
+		if rev := m.currentReview(); rev != nil && m.finding < len(rev.Findings)-1 {
+			return m, m.selectFinding(m.finding + 1)
+		}
+	case key.Matches(msg, m.keys.PrevFinding):
+		if m.finding > 0 {
+			return m, m.selectFinding(m.finding - 1)
+		}
+	case key.Matches(msg, m.keys.Diff):
+		m.showDiff = !m.showDiff
+		if !m.showDiff && m.focus == focusDiff {
+			m.focus = focusReview
+		}
+		cmd := m.resizeViewport()
+		m.scrollDiffToFinding()
+		return m, cmd
+	case key.Matches(msg, m.keys.Patch):
+		m.showPatch = !m.showPatch
+		m.refreshReview()
+		m.viewport.GotoTop()
+	case key.Matches(msg, m.keys.ApplyWorktree):
+		return m, m.applyCurrentPatch(false)
+	case key.Matches(msg, m.keys.ApplyIndex):
+		return m, m.applyCurrentPatch(true)
+	case key.Matches(msg, m.keys.Chat):
+		return m, m.openChat()
+	case key.Matches(msg, m.keys.Edit):
+		return m, m.openEditor()
+	case key.Matches(msg, m.keys.Rereview):
+		if m.selected >= 0 && m.selected < len(m.files) {
+			// 用户主动要求重新审查，即使 diff 未变化也重新请求 LLM
+			return m, m.startReview(m.files[m.selected], true)
+		}
+	case key.Matches(msg, m.keys.Mark):
+		m.startMarking()
+	case key.Matches(msg, m.keys.Hunks):
+		return m, m.openHunks()
+	case key.Matches(msg, m.keys.Refresh):
+		return m, m.refreshFiles("", nil)
+	case key.Matches(msg, m.keys.Commit):
+		return m, m.startCommit()
 	}
 
 	return m, nil
@@ -235,6 +724,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // View 根据当前状态渲染 TUI。
 func (m Model) View() string {
+	if m.showHelp {
+		return m.viewHelp()
+	}
+
 	if m.loading {
 		return m.viewLoading()
 	}
@@ -271,61 +764,160 @@ func (m Model) viewContent() string {
 		return centerInTerminal(infoStyle.Render(msg), m.width, m.height)
 	}
 
-	// 简单的左右布局：左侧文件列表，右侧审查内容
-	totalWidth := m.width
-	if totalWidth <= 0 {
-		totalWidth = 100
-	}
-
-	leftWidth := totalWidth / 4
-	if leftWidth < 20 {
-		leftWidth = 20
-	}
-
-	rightWidth := totalWidth - leftWidth - 4
-	if rightWidth < 20 {
-		rightWidth = 20
-	}
+	// 左右布局：左侧文件列表，右侧可滚动的审查内容
+	leftWidth, diffWidth, rightWidth, bodyHeight := m.layout()
 
-	// 构造文件列表
+	// 构造文件列表（分组审查时为可折叠的包树）
 	var fileLines []string
-	for i, f := range m.files {
-		line := f
-		if i == m.selected {
-			line = selectedFileStyle.Render("> " + f)
-		} else {
-			line = normalFileStyle.Render("  " + f)
+	for _, row := range m.treeRows() {
+		f := m.files[row.unit]
+		var line string
+		// 正在审查的文件以 "…" 标出，外发策略不允许发送的以 "⊘" 标出，审查失败的以 "✗" 标出，
+		// 结果来自缓存的以 "⚡" 标出；整体审查附上整体风险评级。标记只加在单元所在的行上
+		name := m.treeLabel(row)
+		if f == review.OverallFile {
+			name += m.overallLabel()
+		}
+		switch {
+		case row.file != "":
+		case m.reviewing[f]:
+			name += " …"
+		case errors.Is(m.failed[f], policy.ErrDenied):
+			name += " ⊘"
+		case m.failed[f] != nil:
+			name += " ✗"
+		case m.reviews[f] != nil && m.reviews[f].Cached:
+			name += " ⚡"
+		}
+		// 未选中的文件按风险评分着色：高风险红色、中风险橙色
+		switch level := m.rowScore(row).Level(); {
+		case row.unit == m.selected && row.file == m.child:
+			line = selectedFileStyle.Render("> " + name)
+		case level != review.SeverityLow:
+			line = normalFileStyle.Foreground(severityColors[level]).Render("  " + name)
+		default:
+			line = normalFileStyle.Render("  " + name)
 		}
 		fileLines = append(fileLines, line)
 	}
 
+	listStyle := fileListStyle
+	if m.focus == focusFiles && !m.chatOpen {
+		listStyle = listStyle.BorderForeground(lipgloss.Color("62"))
+	}
 	fileList := strings.Join(fileLines, "\n")
-	fileListBox := fileListStyle.
+	fileListBox := listStyle.
 		Width(leftWidth).
+		Height(bodyHeight - 2).
 		Render(fileList)
 
-	// 当前选中文件对应的审查结果
-	var reviewMD string
-	if m.selected >= 0 && m.selected < len(m.files) {
-		file := m.files[m.selected]
-		reviewMD = m.reviews[file]
-		if strings.TrimSpace(reviewMD) == "" {
-			reviewMD = "_该文件暂无审查结果。_"
-		}
-	} else {
-		reviewMD = "_未选中文件。_"
+	var rightPane string
+	switch {
+	case m.chatOpen:
+		rightPane = m.viewChat(rightWidth, bodyHeight)
+	case m.hunks != nil:
+		rightPane = m.viewHunks()
+	default:
+		rightPane = m.viewport.View()
 	}
-
-	renderedReview := renderMarkdown(reviewMD, rightWidth)
 	reviewBox := reviewStyle.
 		Width(rightWidth).
-		Render(renderedReview)
+		Render(rightPane)
+
+	panes := []string{fileListBox}
+	if m.showDiff {
+		boxStyle := diffBoxStyle
+		if m.focus == focusDiff && !m.chatOpen {
+			boxStyle = boxStyle.BorderForeground(lipgloss.Color("62"))
+		}
+		panes = append(panes, boxStyle.
+			Width(diffWidth-2).
+			Height(bodyHeight-2).
+			Render(m.diffView.View()))
+	}
+	panes = append(panes, reviewBox)
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top, panes...)
+
+	parts := []string{body}
+	if m.searching {
+		parts = append(parts, m.search.View())
+	}
+	if m.reasoning {
+		parts = append(parts, m.reasonInput.View())
+	}
+	parts = append(parts, m.viewStatus())
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
 
-	return lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		fileListBox,
-		reviewBox,
+// patchPreviewMarkdown 以 Markdown 形式展示当前选中问题的补丁及其校验结果。
+func (m Model) patchPreviewMarkdown() string {
+	f := m.currentFinding()
+	if f == nil || f.Patch == "" {
+		if f != nil && f.Verification != nil {
+			return fmt.Sprintf("_补丁%s，已被丢弃。按 p 返回审查报告。_\n\n```text\n%s\n```\n", f.Verification.Summary(), f.Verification.Output)
+		}
+		return "_当前问题没有建议的补丁。按 p 返回审查报告。_"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## 补丁预览：%s\n\n", f.Title)
+	switch {
+	case m.applied[findingKey(m.files[m.selected], m.finding)]:
+		b.WriteString("_该补丁已应用。_\n\n")
+	case f.PatchErr != "":
+		fmt.Fprintf(&b, "_git apply --check 校验失败：%s_\n\n", f.PatchErr)
+	default:
+		b.WriteString("_已通过 git apply --check 校验：按 a 应用到工作区，按 A 应用到暂存区（应用前会针对目标重新校验）。_\n\n")
+	}
+	if v := f.Verification; v != nil {
+		fmt.Fprintf(&b, "**临时 worktree 验证：%s**\n\n", v.Summary())
+		if !v.Passed && v.Output != "" {
+			fmt.Fprintf(&b, "```text\n%s\n```\n\n", v.Output)
+		}
+	}
+	fmt.Fprintf(&b, "```diff\n%s```\n", f.Patch)
+
+	return b.String()
+}
+
+// viewStatus 渲染底部状态栏：最近一次操作结果、滚动位置、本次运行的 LLM 用量与常用快捷键。
+func (m Model) viewStatus() string {
+	parts := []string{fmt.Sprintf("%3.0f%%", m.viewport.ScrollPercent()*100)}
+	if m.status != "" {
+		parts = append(parts, m.status)
+	}
+	if m.runner.DryRun() {
+		parts = append(parts, "试运行：未发送任何请求")
+	}
+	if t, ok := m.runner.Usage(); ok && t.Requests > 0 {
+		parts = append(parts, t.String())
+	}
+	if t, ok := m.runner.Usage(); ok && t.Warning != "" {
+		parts = append(parts, t.Warning)
+	}
+	parts = append(parts, m.help.ShortHelpView(m.keys.ShortHelp()))
+
+	line := infoStyle.Render(strings.Join(parts, "  |  "))
+	if m.width > 0 {
+		// 状态栏超出终端宽度时会折行并把整个界面顶上去，因此截断到一行
+		line = ansi.Truncate(line, m.width, "…")
+	}
+	return line
+}
+
+// viewHelp 渲染 "?" 帮助面板，展示全部快捷键。
+func (m Model) viewHelp() string {
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		"快捷键",
+		"",
+		m.help.FullHelpView(m.keys.FullHelp()),
+		"",
+		"按任意键返回",
 	)
+	return centerInTerminal(helpStyle.Render(content), m.width, m.height)
 }
 
 // renderMarkdown 使用 glamour 渲染 Markdown 内容，如果失败则退回原始文本。
@@ -364,54 +956,3 @@ func centerInTerminal(content string, width, height int) string {
 
 	return box
 }
-
-// buildReviewPrompt 根据 Git diff 构造发送给 LLM 的审查提示词。
-// 这里复用原先 Reviewer 中的系统说明，只是将其合并为一条用户消息，
-// 以便通过通用的 Chat 接口发送。
-func buildReviewPrompt(diff string) string {
-	diff = strings.TrimSpace(diff)
-	if diff == "" {
-		return "暂存区 diff 为空，无需审查。"
-	}
-
-	systemPrompt := `你是一名资深 Golang 专家，擅长设计高可读性、可维护且鲁棒的 Go 代码。
-现在请你扮演“代码审查助手”，针对给定的 Git diff 进行严格的代码评审，重点关注：
-
-1. 安全性：
-   - 输入校验是否充分
-   - 是否存在潜在的注入风险、越界访问、竞争条件等
-   - 敏感信息（如密钥、token、密码）是否有泄露风险
-
-2. 错误处理：
-   - 错误是否被忽略或吞掉
-   - 错误信息是否清晰、能帮助定位问题
-   - 是否合理使用 error wrapping 以及日志
-
-3. 性能与资源使用：
-   - 算法与数据结构是否合理
-   - 是否存在明显的多余分配或重复计算
-   - I/O、网络、并发是否可能成为瓶颈
-
-请以 Markdown 格式输出审查结果，建议结构示例：
-
-## 总体评价
-- 简要评价这次变更的整体质量。
-
-## 主要风险与问题
-- 按严重程度列出主要问题，并引用相关代码片段或行号（如果 diff 中有）。
-
-## 优化建议
-- 给出可以改进的地方，包括安全、错误处理和性能方面的具体建议。
-
-## 认可的优点
-- 指出本次改动中值得保留或学习的写法。
-
-回复时只需要给出审查内容，无需重复贴出完整 diff。`
-
-	userPrompt := fmt.Sprintf(
-		"请审查以下 Git diff（只读即可，不需要给出可直接应用的 patch），并按照上述要求返回 Markdown 格式的审查报告：\n\n```diff\n%s\n```",
-		diff,
-	)
-
-	return systemPrompt + "\n\n" + userPrompt
-}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// displayName 返回文件列表与状态栏中展示的名称：整体审查显示为 "★ 整体审查"，其他文件原样显示。
+func displayName(file string) string {
+	if file == review.OverallFile {
+		return "★ 整体审查"
+	}
+	return file
+}
+
+// reviewOverallCmd 在后台重新进行整体审查，完成后发送 fileReviewedMsg。
+// reviews 为各文件审查结果的快照，diff 过长时用作摘要。
+func reviewOverallCmd(runner *review.Runner, reviews map[string]*review.FileReview, fresh bool) tea.Cmd {
+	return func() tea.Msg {
+		if runner == nil {
+			return fileReviewedMsg{file: review.OverallFile, err: errors.New("审查流程未初始化")}
+		}
+		rev, err := runner.ReviewOverall(reviews, fresh)
+		return fileReviewedMsg{file: review.OverallFile, review: rev, err: err}
+	}
+}
+
+// hasOverall 报告文件列表中是否有整体审查一项（总是排在第一位）。
+func (m Model) hasOverall() bool {
+	return len(m.files) > 0 && m.files[0] == review.OverallFile
+}
+
+// startOverall 开始重新进行整体审查，审查进行中时不重复发起。
+func (m *Model) startOverall(fresh bool) tea.Cmd {
+	if m.reviewing[review.OverallFile] {
+		m.status = "整体审查正在进行中"
+		return nil
+	}
+
+	// 后台任务只读取快照，避免与 UI 对 m.reviews 的修改同时进行
+	reviews := make(map[string]*review.FileReview, len(m.reviews))
+	for f, rev := range m.reviews {
+		if f != review.OverallFile {
+			reviews[f] = rev
+		}
+	}
+
+	m.overallPending = false
+	m.reviewing[review.OverallFile] = true
+	m.status = "正在整体审查全部变更..."
+	m.refreshReview()
+	return reviewOverallCmd(m.runner, reviews, fresh)
+}
+
+// reviewingFiles 报告是否还有文件（不含整体审查）正在审查中。
+func (m Model) reviewingFiles() bool {
+	for f := range m.reviewing {
+		if f != review.OverallFile {
+			return true
+		}
+	}
+	return false
+}
+
+// currentFile 返回与当前选中项对应的真实文件，用于 diff 面板、hunk 面板与编辑器：
+// 分组审查中选中了包下的文件时为该文件；选中整体审查或包本身时为选中问题所在的文件，
+// 问题没有注明文件时为空。
+func (m Model) currentFile() string {
+	if m.selected < 0 || m.selected >= len(m.files) {
+		return ""
+	}
+	if m.child != "" {
+		return m.child
+	}
+	key := m.files[m.selected]
+	if key != review.OverallFile && len(m.members[key]) == 0 {
+		return key
+	}
+	if f := m.currentFinding(); f != nil && f.File != key && f.File != review.OverallFile {
+		return f.File
+	}
+	return ""
+}
+
+// overallLabel 返回文件列表中整体审查一项附加的风险评级，例如 " [high]"；尚无评级时为空。
+func (m Model) overallLabel() string {
+	rev := m.reviews[review.OverallFile]
+	if rev == nil || rev.Risk == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", rev.Risk)
+}
@@ -0,0 +1,227 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/GuLuGuLuGit/review-go/internal/policy"
+	"github.com/GuLuGuLuGit/review-go/internal/redact"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+	"github.com/GuLuGuLuGit/review-go/internal/usage"
+)
+
+// focusArea 表示当前接收方向键的面板。
+type focusArea int
+
+const (
+	focusFiles focusArea = iota
+	focusDiff
+	focusReview
+)
+
+// findingMarker 是 review.FindingsMarkdown 标记选中问题时使用的前缀，用于在渲染结果中定位该行。
+const findingMarker = "▶"
+
+// newSearchInput 创建 "/" 搜索输入框。
+func newSearchInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "在当前审查中搜索，回车确认，Esc 取消"
+	ti.CharLimit = 200
+	return ti
+}
+
+// layout 根据终端尺寸计算左侧文件列表、中间 diff 面板（隐藏时为 0）与右侧审查内容的宽度，
+// 以及正文区域的高度。底部保留一行状态栏，搜索或填写忽略原因时再保留一行输入框。
+func (m Model) layout() (leftWidth, diffWidth, rightWidth, bodyHeight int) {
+	totalWidth := m.width
+	if totalWidth <= 0 {
+		totalWidth = 100
+	}
+
+	leftWidth = totalWidth / 4
+	if leftWidth < 20 {
+		leftWidth = 20
+	}
+
+	rightWidth = totalWidth - leftWidth - 4
+	if rightWidth < 20 {
+		rightWidth = 20
+	}
+
+	if m.showDiff {
+		diffWidth = rightWidth / 2
+		rightWidth -= diffWidth
+	}
+
+	bodyHeight = m.height - 1
+	if m.searching || m.reasoning {
+		bodyHeight--
+	}
+	if bodyHeight < 5 {
+		bodyHeight = 5
+	}
+
+	return leftWidth, diffWidth, rightWidth, bodyHeight
+}
+
+// resizeViewport 让审查内容与 diff 面板的 viewport 适配当前布局，并重新渲染内容（换行宽度可能变化）。
+func (m *Model) resizeViewport() tea.Cmd {
+	_, diffWidth, rightWidth, bodyHeight := m.layout()
+	m.viewport.Width = rightWidth
+	m.viewport.Height = bodyHeight
+	m.refreshReview()
+
+	// diff 面板带边框与左右内边距
+	m.diffView.Width = diffWidth - 4
+	m.diffView.Height = bodyHeight - 2
+	m.refreshHunks()
+	return m.refreshDiff()
+}
+
+// activeViewport 返回当前焦点对应的可滚动面板：diff 面板获得焦点时为 diff，否则为审查内容。
+func (m *Model) activeViewport() *viewport.Model {
+	if m.focus == focusDiff && m.showDiff {
+		return &m.diffView
+	}
+	return &m.viewport
+}
+
+// reviewMarkdown 返回当前选中文件对应的审查结果，或选中问题的补丁预览。
+func (m Model) reviewMarkdown() string {
+	if m.selected < 0 || m.selected >= len(m.files) {
+		return "_未选中文件。_"
+	}
+
+	var md string
+	if m.showPatch {
+		md = m.patchPreviewMarkdown()
+	} else if rev := m.currentReview(); rev != nil {
+		md = rev.Markdown
+		if findings := review.FindingsMarkdown(rev.File, rev.Findings, m.finding); findings != "" {
+			md += "\n\n" + findings
+		}
+		if t, ok := m.runner.Usage(); ok && rev.Usage.Total() > 0 {
+			label := "本文件用量"
+			if m.runner.DryRun() {
+				label = "本文件估算用量"
+			}
+			md += "\n\n_" + label + "：" + usage.Format(rev.Usage, t.Currency, t.Priced) + "_"
+		}
+	}
+	if strings.TrimSpace(md) == "" {
+		md = "_该文件暂无审查结果。_"
+	}
+
+	// 重新审查进行中或失败时，在旧结果（如果有）之上给出提示
+	file := m.files[m.selected]
+	switch {
+	case m.reviewing[file]:
+		md = "_正在审查该文件..._\n\n" + md
+	case errors.Is(m.failed[file], policy.ErrDenied):
+		md = fmt.Sprintf("**未发送：** %v\n\n_该文件没有发送给 LLM，可以在配置的 egress.rules 中调整。_\n\n", m.failed[file]) + md
+	case m.failed[file] != nil:
+		md = fmt.Sprintf("**审查失败：** %v\n\n_按 r 重试。_\n\n", m.failed[file]) + md
+	case !m.showPatch && m.currentReview() != nil && m.currentReview().Cached:
+		md = "_⚡ 该结果来自缓存，按 r 重新请求 LLM。_\n\n" + md
+	}
+	if rev := m.currentReview(); !m.showPatch && rev != nil && len(rev.Redactions) > 0 {
+		md = fmt.Sprintf("_🔒 发送前已脱敏 %d 处：%s_\n\n", len(rev.Redactions), redact.Summary(rev.Redactions)) + md
+	}
+	if score, ok := m.scores[file]; ok && !m.showPatch {
+		md = fmt.Sprintf("_风险评分：%s_\n\n", score) + md
+	}
+
+	return md
+}
+
+// refreshReview 重新渲染右侧审查内容并写入 viewport。
+//
+// glamour 渲染代价较高，因此只在选中项、窗口尺寸或内容变化时调用，而不是每次 View 都渲染。
+// 已有搜索关键字时会重新计算匹配行。
+func (m *Model) refreshReview() {
+	rendered := renderMarkdown(m.reviewMarkdown(), m.viewport.Width)
+	m.reviewLines = strings.Split(ansi.Strip(rendered), "\n")
+	m.viewport.SetContent(rendered)
+	m.findMatches()
+}
+
+// scrollToFinding 滚动 viewport，使选中问题所在的行可见。
+func (m *Model) scrollToFinding() {
+	for i, line := range m.reviewLines {
+		if strings.Contains(line, findingMarker) {
+			m.scrollToLine(i)
+			return
+		}
+	}
+}
+
+// scrollToLine 在目标行不可见时滚动 viewport，使其位于可视区域上方约三分之一处。
+func (m *Model) scrollToLine(line int) {
+	top := m.viewport.YOffset
+	if line >= top && line < top+m.viewport.Height {
+		return
+	}
+	m.viewport.SetYOffset(line - m.viewport.Height/3)
+}
+
+// updateSearch 处理搜索输入框打开时的按键：回车确认并跳到第一个匹配，Esc 取消。
+func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		return m, m.resizeViewport()
+
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		m.query = strings.TrimSpace(m.search.Value())
+		cmd := m.resizeViewport()
+		m.match = -1
+		m.jumpMatch(1)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	return m, cmd
+}
+
+// findMatches 在渲染后的纯文本中查找包含关键字的行（不区分大小写）。
+func (m *Model) findMatches() {
+	m.matches = nil
+	m.match = -1
+	if m.query == "" {
+		return
+	}
+
+	q := strings.ToLower(m.query)
+	for i, line := range m.reviewLines {
+		if strings.Contains(strings.ToLower(line), q) {
+			m.matches = append(m.matches, i)
+		}
+	}
+}
+
+// jumpMatch 跳到下一个（dir > 0）或上一个匹配，首尾循环，并在状态栏中显示进度。
+func (m *Model) jumpMatch(dir int) {
+	if m.query == "" {
+		return
+	}
+	if len(m.matches) == 0 {
+		m.status = fmt.Sprintf("未找到 %q", m.query)
+		return
+	}
+
+	n := len(m.matches)
+	m.match = ((m.match+dir)%n + n) % n
+	m.scrollToLine(m.matches[m.match])
+	m.status = fmt.Sprintf("%q：第 %d/%d 处匹配", m.query, m.match+1, n)
+}
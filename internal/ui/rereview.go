@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// fileReviewedMsg 是重新审查单个文件（或审查单元）完成后发送给 UI 的消息。
+type fileReviewedMsg struct {
+	file   string
+	review *review.FileReview
+	err    error
+}
+
+// filesRefreshedMsg 是重新读取暂存区文件列表完成后发送给 UI 的消息。
+//
+// - files: 最新的暂存区文件列表，已按风险评分排序并按 --max-files 筛选；分组审查时为各审查单元的 key
+// - members: 分组审查时每个单元包含的文件
+// - changed: 其中 diff 与上次审查时不同（或尚未审查过）的单元
+// - scores / skipped: 重新计算的风险评分与因 --max-files 限制而不审查的文件
+// - skippedAPI: skipped 中有导出 API 不兼容变化的文件的检查结果（见 review.Runner.SkippedReviews），排在列表末尾
+type filesRefreshedMsg struct {
+	files      []string
+	members    map[string][]string
+	changed    []string
+	scores     map[string]review.RiskScore
+	skipped    []string
+	skippedAPI []*review.FileReview
+	err        error
+}
+
+// reviewFileCmd 在后台重新审查单个文件（分组审查时为一个包或模块），完成后发送 fileReviewedMsg。
+// fresh 为 true 时跳过 LLM 回复缓存。
+func reviewFileCmd(runner *review.Runner, u review.Unit, fresh bool) tea.Cmd {
+	return func() tea.Msg {
+		if runner == nil {
+			return fileReviewedMsg{file: u.Key, err: errors.New("审查流程未初始化")}
+		}
+
+		diags := runner.Analyze(u.Files)
+		rev, err := runner.ReviewUnit(u, diags, fresh)
+		return fileReviewedMsg{file: u.Key, review: rev, err: err}
+	}
+}
+
+// refreshFilesCmd 在后台重新读取暂存区文件列表并划分审查单元，与 reviewed（单元 → 上次审查时的 diff）比较，
+// 找出需要重新审查的单元，完成后发送 filesRefreshedMsg。
+// stage 不为空时先把该文件在工作区中的修改加入暂存区，因为审查针对的是暂存区的 diff；
+// keep 中的 hunk（编辑之前就未暂存的修改，见 gitops.StageHunksExcept）保持不暂存。
+func refreshFilesCmd(runner *review.Runner, reviewed map[string]string, stage string, keep map[string]bool) tea.Cmd {
+	return func() tea.Msg {
+		if runner == nil {
+			return filesRefreshedMsg{err: errors.New("审查流程未初始化")}
+		}
+		if stage != "" {
+			if _, err := gitops.StageHunksExcept(stage, keep); err != nil {
+				return filesRefreshedMsg{err: fmt.Errorf("暂存 %s 失败：%w", stage, err)}
+			}
+		}
+
+		files, err := runner.ChangedFiles()
+		if err != nil {
+			return filesRefreshedMsg{err: fmt.Errorf("获取暂存区文件失败：%w", err)}
+		}
+		triage := runner.Triage(files)
+
+		units := append(runner.DepsUnits(), runner.Units(triage.Files)...)
+		keys := make([]string, 0, len(units))
+		members := make(map[string][]string)
+		var changed []string
+		for _, u := range units {
+			keys = append(keys, u.Key)
+			if runner.Grouped() && !review.IsModFile(u.Key) {
+				members[u.Key] = u.Files
+			}
+			diff, err := runner.UnitDiff(u)
+			if prev, ok := reviewed[u.Key]; ok && err == nil && diff == prev {
+				continue
+			}
+			changed = append(changed, u.Key)
+		}
+
+		return filesRefreshedMsg{
+			files:      keys,
+			members:    members,
+			changed:    changed,
+			scores:     triage.Scores,
+			skipped:    triage.Skipped,
+			skippedAPI: runner.SkippedReviews(triage.Skipped),
+		}
+	}
+}
+
+// startReview 开始重新审查 key 对应的文件（或包、模块），审查进行中时不重复发起。参数含义见 reviewFileCmd。
+// key 为整体审查时重新进行整体审查。
+func (m *Model) startReview(key string, fresh bool) tea.Cmd {
+	if key == review.OverallFile {
+		return m.startOverall(fresh)
+	}
+	if m.reviewing[key] {
+		m.status = fmt.Sprintf("%s 正在审查中", key)
+		return nil
+	}
+
+	m.reviewing[key] = true
+	m.status = fmt.Sprintf("正在重新审查 %s...", key)
+	m.refreshReview()
+	return reviewFileCmd(m.runner, m.unit(key), fresh)
+}
+
+// refreshFiles 发起刷新：以各单元上次审查时的 diff 作为比较基准，重新审查 diff 有变化的单元。
+// stage 不为空时先暂存该文件中除 keep 以外的修改，用于编辑器中修改文件之后。
+func (m *Model) refreshFiles(stage string, keep map[string]bool) tea.Cmd {
+	reviewed := make(map[string]string, len(m.reviews))
+	for f, rev := range m.reviews {
+		reviewed[f] = rev.Diff
+	}
+
+	m.status = "正在刷新暂存区文件列表..."
+	if stage != "" {
+		m.status = fmt.Sprintf("正在暂存 %s 并刷新...", stage)
+	}
+	return refreshFilesCmd(m.runner, reviewed, stage, keep)
+}
+
+// handleFileReviewed 用重新审查的结果替换该文件原有的结果，并清理依赖旧结果的状态。
+// 审查失败时保留旧结果，把错误记录下来以便原地重试。
+// 整体审查已过期且所有文件都审查完毕时，接着重新进行整体审查。
+func (m *Model) handleFileReviewed(msg fileReviewedMsg) tea.Cmd {
+	cmd := m.replaceReview(msg)
+	if m.overallPending && m.hasOverall() && !m.reviewingFiles() {
+		return tea.Batch(cmd, m.startOverall(false))
+	}
+	return cmd
+}
+
+// replaceReview 实现 handleFileReviewed 中对单个结果的处理。
+// 失败但带回了不依赖 LLM 的结果（例如依赖检查）时，同样用它替换旧结果，并记录错误。
+func (m *Model) replaceReview(msg fileReviewedMsg) tea.Cmd {
+	delete(m.reviewing, msg.file)
+	if msg.err != nil && msg.review == nil {
+		m.failed[msg.file] = msg.err
+		m.status = fmt.Sprintf("审查 %s 失败，按 r 重试", displayName(msg.file))
+		if m.isSelected(msg.file) {
+			m.refreshReview()
+		}
+		return nil
+	}
+
+	delete(m.failed, msg.file)
+	m.reviews[msg.file] = msg.review
+	m.forgetFile(msg.file)
+	m.status = fmt.Sprintf("已重新审查 %s", displayName(msg.file))
+	if msg.err != nil {
+		m.failed[msg.file] = msg.err
+		m.status = fmt.Sprintf("审查 %s 失败，已更新不依赖 LLM 的结果，按 r 重试", displayName(msg.file))
+	}
+
+	if !m.isSelected(msg.file) {
+		return nil
+	}
+	m.finding = 0
+	m.showPatch = false
+	m.refreshReview()
+	return m.refreshDiff()
+}
+
+// handleFilesRefreshed 用最新的文件列表替换当前列表：移除已不在暂存区中的文件，
+// 保留 diff 未变化的审查结果，并依次重新审查其余文件；有文件需要重新审查时整体审查随后更新。
+func (m *Model) handleFilesRefreshed(msg filesRefreshedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("刷新失败：%v", msg.err)
+		return nil
+	}
+	if m.runner.WantsOverall(msg.files) {
+		msg.files = append([]string{review.OverallFile}, msg.files...)
+		m.overallPending = m.overallPending || len(msg.changed) > 0 || m.reviews[review.OverallFile] == nil
+	} else {
+		m.overallPending = false
+	}
+	for _, rev := range msg.skippedAPI {
+		msg.files = append(msg.files, rev.File)
+	}
+
+	current := ""
+	if m.selected >= 0 && m.selected < len(m.files) {
+		current = m.files[m.selected]
+	}
+
+	present := make(map[string]bool, len(msg.files))
+	for _, f := range msg.files {
+		present[f] = true
+	}
+	for _, f := range m.files {
+		if !present[f] {
+			delete(m.reviews, f)
+			delete(m.failed, f)
+			m.forgetFile(f)
+		}
+	}
+
+	// 尽量保持原来选中的文件；它已被移除时回到第一个文件
+	m.files = msg.files
+	for _, f := range msg.changed {
+		m.forgetFile(f) // 在替换 members 之前清理，以覆盖被移出该单元的文件
+	}
+	m.members = msg.members
+	if !slices.Contains(m.members[current], m.child) {
+		m.child = ""
+	}
+	m.scores = msg.scores
+	m.skipped = msg.skipped
+	for _, rev := range msg.skippedAPI {
+		// diff 未变化时保留原有结果，它可能是用户按 r 主动发起的完整审查
+		if old := m.reviews[rev.File]; old != nil && old.Diff == rev.Diff {
+			continue
+		}
+		delete(m.failed, rev.File)
+		m.reviews[rev.File] = rev
+		m.forgetFile(rev.File)
+	}
+	m.selected = 0
+	for i, f := range m.files {
+		if f == current {
+			m.selected = i
+		}
+	}
+	if !m.isSelected(current) {
+		m.finding = 0
+		m.showPatch = false
+	}
+
+	var cmds []tea.Cmd
+	for _, f := range msg.changed {
+		if m.reviewing[f] {
+			continue
+		}
+		m.reviewing[f] = true
+		m.forgetFile(f)
+		cmds = append(cmds, reviewFileCmd(m.runner, m.unit(f), false))
+	}
+
+	m.refreshReview()
+	if len(cmds) == 0 {
+		m.status = "暂存区没有变化，保留现有审查结果"
+		if len(m.skipped) > 0 {
+			m.status += "；" + m.skippedStatus()
+		}
+		if m.overallPending && !m.reviewingFiles() {
+			return tea.Batch(m.refreshDiff(), m.startOverall(false))
+		}
+		return m.refreshDiff()
+	}
+
+	m.status = fmt.Sprintf("正在重新审查 %d 个 diff 有变化的文件...", len(cmds))
+	// 逐个审查，避免同时向 LLM 发起大量请求；每个文件完成后立即更新界面
+	return tea.Batch(m.refreshDiff(), tea.Sequence(cmds...))
+}
+
+// forgetFile 清理依赖 file 旧 diff 或旧审查结果的缓存状态：补丁应用记录（按问题下标记录）、
+// diff 面板缓存（分组审查时包括单元中每个文件的）以及以旧审查结果为上下文的追问对话（正在进行的对话除外）。
+func (m *Model) forgetFile(file string) {
+	prefix := file + "#"
+	for k := range m.applied {
+		if strings.HasPrefix(k, prefix) {
+			delete(m.applied, k)
+		}
+	}
+	delete(m.diffs, file)
+	for _, f := range m.members[file] {
+		delete(m.diffs, f)
+	}
+	if !(m.chatOpen && m.isSelected(file)) {
+		delete(m.chats, file)
+	}
+}
+
+// isSelected 判断 file 是否为当前选中的文件。
+func (m Model) isSelected(file string) bool {
+	return m.selected >= 0 && m.selected < len(m.files) && m.files[m.selected] == file
+}
@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/GuLuGuLuGit/review-go/internal/review"
+)
+
+// treeRow 是左侧列表中的一行：审查单元本身（file 为空），或分组审查时单元下的一个文件。
+type treeRow struct {
+	unit int
+	file string
+}
+
+// treeRows 返回左侧列表当前可见的行。逐个文件审查时每个文件一行；分组审查时每个包（模块）一行，
+// 展开的包下面再列出其中的文件。
+func (m Model) treeRows() []treeRow {
+	rows := make([]treeRow, 0, len(m.files))
+	for i, key := range m.files {
+		rows = append(rows, treeRow{unit: i})
+		if m.collapsed[key] {
+			continue
+		}
+		for _, f := range m.members[key] {
+			rows = append(rows, treeRow{unit: i, file: f})
+		}
+	}
+	return rows
+}
+
+// moveSelection 在左侧列表的可见行之间上下移动 delta 行。
+func (m *Model) moveSelection(delta int) tea.Cmd {
+	rows := m.treeRows()
+	cur := 0
+	for i, r := range rows {
+		if r.unit == m.selected && r.file == m.child {
+			cur = i
+			break
+		}
+	}
+
+	next := cur + delta
+	if next < 0 || next >= len(rows) {
+		return nil
+	}
+	row := rows[next]
+	if row.unit == m.selected {
+		return m.selectChild(row.file)
+	}
+
+	cmd := m.selectFile(row.unit)
+	if row.file == "" {
+		return cmd
+	}
+	return tea.Batch(cmd, m.selectChild(row.file))
+}
+
+// selectChild 选中当前单元下的文件 file（为空表示单元本身）：选中该文件中的第一个问题，
+// diff 面板随之切换到该文件。
+func (m *Model) selectChild(file string) tea.Cmd {
+	m.child = file
+	if rev := m.currentReview(); rev != nil && file != "" {
+		for i, f := range rev.Findings {
+			if f.File == file {
+				return m.selectFinding(i)
+			}
+		}
+	}
+	m.diffView.GotoTop()
+	return m.refreshDiff()
+}
+
+// toggleFold 折叠或展开当前选中的包（模块）：left 折叠，right 展开，其他键切换。
+// 折叠时选中项回到包本身。
+func (m *Model) toggleFold(k string) tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.files) {
+		return nil
+	}
+	key := m.files[m.selected]
+	if len(m.members[key]) == 0 {
+		return nil
+	}
+
+	switch k {
+	case "left":
+		m.collapsed[key] = true
+	case "right":
+		m.collapsed[key] = false
+	default:
+		m.collapsed[key] = !m.collapsed[key]
+	}
+	if m.collapsed[key] && m.child != "" {
+		m.child = ""
+		return m.refreshDiff()
+	}
+	return nil
+}
+
+// unit 返回 key 对应的审查单元。
+func (m Model) unit(key string) review.Unit {
+	files := m.members[key]
+	if len(files) == 0 {
+		files = []string{key}
+	}
+	return review.Unit{Key: key, Files: files}
+}
+
+// treeLabel 返回左侧列表中一行的文字（不含选中标记与状态标记）。
+func (m Model) treeLabel(row treeRow) string {
+	key := m.files[row.unit]
+	if row.file != "" {
+		return "  " + path.Base(row.file)
+	}
+	if files := m.members[key]; len(files) > 0 {
+		fold := "▾ "
+		if m.collapsed[key] {
+			fold = "▸ "
+		}
+		return fmt.Sprintf("%s%s (%d)", fold, key, len(files))
+	}
+	return displayName(key)
+}
+
+// rowScore 返回一行对应的风险评分：文件取自身的评分，包（模块）取其中评分最高的文件。
+func (m Model) rowScore(row treeRow) review.RiskScore {
+	if row.file != "" {
+		return m.scores[row.file]
+	}
+	key := m.files[row.unit]
+	best := m.scores[key]
+	for _, f := range m.members[key] {
+		if s := m.scores[f]; s.Score > best.Score {
+			best = s
+		}
+	}
+	return best
+}
@@ -0,0 +1,173 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/gitops"
+)
+
+// ledgerFile 是每天用量在状态目录中的文件名。
+const ledgerFile = "usage.json"
+
+// keepDays 是 Ledger 保留的天数，更早的记录在写入时清理。
+const keepDays = 90
+
+// lockTimeout 是等待其他进程释放用量记录锁的最长时间；staleLock 之前创建的锁文件
+// 视为崩溃的进程遗留下来的，直接删除。
+const (
+	lockTimeout = 3 * time.Second
+	staleLock   = 30 * time.Second
+)
+
+// Ledger 按天记录当前仓库的 LLM 用量，保存为 JSON：{"2006-01-02": {...}}。
+//
+// 每次读写都直接读取文件，同时运行的多个 review-go（例如 TUI 与 Git 钩子）能看到彼此的用量。
+// 写入时持有锁文件（usage.json.lock），先写临时文件再重命名，读取方不会看到写了一半的内容，
+// 多个进程的累加也不会互相覆盖。文件损坏无法解析时按空记录处理，并通过 Warning 报告。
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+
+	warning string
+}
+
+// OpenLedger 返回当前仓库的用量记录（<git-common-dir>/review-go/usage.json）。
+func OpenLedger() (*Ledger, error) {
+	dir, err := gitops.GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Ledger{path: filepath.Join(dir, ledgerFile)}, nil
+}
+
+// Day 返回 t 所在当天（本地时间）的累计用量。
+func (l *Ledger) Day(t time.Time) (ai.Usage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	days, err := l.load()
+	if err != nil {
+		return ai.Usage{}, err
+	}
+	return days[dayKey(t)], nil
+}
+
+// Warning 返回读取用量记录时遇到的问题（例如文件损坏、已从零开始统计），没有时返回空字符串。
+func (l *Ledger) Warning() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.warning
+}
+
+// Add 把 u 累加到 t 所在当天的用量上，并清理超过 keepDays 的旧记录。
+func (l *Ledger) Add(t time.Time, u ai.Usage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	days, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	key := dayKey(t)
+	day := days[key]
+	day.Add(u)
+	days[key] = day
+
+	cutoff := dayKey(t.AddDate(0, 0, -keepDays))
+	for k := range days {
+		if k < cutoff {
+			delete(days, k)
+		}
+	}
+
+	data, err := json.MarshalIndent(days, "", "  ")
+	if err != nil {
+		return err
+	}
+	return l.write(data)
+}
+
+// write 先把 data 写入同目录的临时文件再重命名为用量记录，避免读取方看到写了一半的文件。
+func (l *Ledger) write(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ledgerFile+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("写入用量记录 %s 失败：%w", l.path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入用量记录 %s 失败：%w", l.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入用量记录 %s 失败：%w", l.path, err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入用量记录 %s 失败：%w", l.path, err)
+	}
+	return nil
+}
+
+// lock 创建锁文件，阻止其他进程同时修改用量记录，返回释放锁的函数。
+// 锁被占用时每隔一小段时间重试，超过 lockTimeout 仍未获得时返回错误；超过 staleLock 的锁文件视为遗留并删除。
+func (l *Ledger) lock() (func(), error) {
+	path := l.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("锁定用量记录失败：%w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("锁定用量记录失败：%s 被其他进程占用", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// load 读取全部记录，文件不存在时返回空记录。文件无法解析（例如旧版本写入时崩溃留下的半个文件）时
+// 同样返回空记录并记下警告：否则按天的预算会一直因读取失败而拒绝全部请求；下一次 Add 会写入完整的新文件。
+func (l *Ledger) load() (map[string]ai.Usage, error) {
+	days := make(map[string]ai.Usage)
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return days, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取用量记录 %s 失败：%w", l.path, err)
+	}
+	if err := json.Unmarshal(data, &days); err != nil {
+		l.warning = fmt.Sprintf("用量记录 %s 已损坏（%v），当天用量从零开始重新统计", l.path, err)
+		return make(map[string]ai.Usage), nil
+	}
+	return days, nil
+}
+
+// dayKey 返回 t 在本地时区的日期，用作记录的 key。
+func dayKey(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
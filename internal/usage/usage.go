@@ -0,0 +1,176 @@
+// Package usage 统计 LLM 请求的 token 用量与费用，并在达到预算上限后阻止继续发送请求。
+//
+// Meter 包装一个 ai.LLMProvider，对经过它的每次请求按配置的模型价格计算费用，
+// 累计单次运行的用量，并把每天的用量写入当前仓库的 Ledger，用于按天的预算。
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GuLuGuLuGit/review-go/internal/ai"
+	"github.com/GuLuGuLuGit/review-go/internal/config"
+)
+
+// ErrBudgetExceeded 表示已经达到配置的预算上限，请求没有发送。
+var ErrBudgetExceeded = errors.New("已达到预算上限")
+
+// Totals 是一次运行累计的用量。
+//
+// - Requests: 实际完成计费的请求数（命中缓存、被预算或脱敏拦截以及未返回用量的失败请求不计入）
+// - Priced: 当前模型是否配置了价格；未配置时 Usage.Cost 始终为 0
+// - Warning: 读取每天用量时遇到的问题（见 Ledger.Warning），没有时为空
+type Totals struct {
+	Usage    ai.Usage
+	Requests int
+	Priced   bool
+	Currency string
+	Warning  string
+}
+
+// String 返回便于在状态栏与报告中展示的用量摘要。
+func (t Totals) String() string {
+	return Format(t.Usage, t.Currency, t.Priced)
+}
+
+// Format 把用量格式化为 "1.2k tokens（输入 1.0k / 输出 200）· $0.0123"；priced 为 false 时省略费用。
+func Format(u ai.Usage, currency string, priced bool) string {
+	s := fmt.Sprintf("%s tokens（输入 %s / 输出 %s）",
+		formatTokens(u.Total()), formatTokens(u.PromptTokens), formatTokens(u.CompletionTokens))
+	if priced {
+		s += fmt.Sprintf(" · %s%.4f", currency, u.Cost)
+	}
+	return s
+}
+
+// formatTokens 把 token 数格式化为 950、1.2k、3.4M 等形式。
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// Meter 统计经过它的请求的用量，并执行预算上限。它嵌入了 ai.Middleware，可以直接替换被包装的 provider。
+//
+// 预算在发送请求之前检查：已累计的用量达到上限后，之后的请求直接返回 ErrBudgetExceeded。
+// 并发的请求在检查时看不到彼此的用量，因此实际用量可能略微超过上限。
+type Meter struct {
+	ai.Middleware
+	price    *config.ModelPrice
+	currency string
+	budget   config.BudgetConfig
+	ledger   *Ledger
+
+	mu       sync.Mutex
+	total    ai.Usage
+	requests int
+}
+
+// NewMeter 包装 provider。价格按 provider 的模型名（忽略大小写）从 cfg.Prices 中查找；
+// ledger 为 nil 时不记录每天的用量，按天的预算也不生效。
+//
+// 配置了费用预算（run_cost / day_cost）但模型没有价格时无法计算费用，预算形同虚设，因此返回错误。
+func NewMeter(provider ai.LLMProvider, cfg config.UsageConfig, ledger *Ledger) (*Meter, error) {
+	m := &Meter{
+		currency: cfg.Currency,
+		budget:   cfg.Budget,
+		ledger:   ledger,
+	}
+	m.Middleware = ai.Middleware{Next: provider, SendFunc: m.send}
+
+	model := ai.IdentityOf(provider).Model
+	for i := range cfg.Prices {
+		if strings.EqualFold(strings.TrimSpace(cfg.Prices[i].Model), model) {
+			m.price = &cfg.Prices[i]
+			break
+		}
+	}
+	if m.price == nil && (cfg.Budget.RunCost > 0 || cfg.Budget.DayCost > 0) {
+		return nil, fmt.Errorf("配置了费用预算（usage.budget.run_cost / day_cost），但模型 %q 没有在 usage.prices 中配置价格，无法按费用限制请求", model)
+	}
+	return m, nil
+}
+
+// send 是嵌入的 ai.Middleware 的 SendFunc：检查预算后发送请求，并把返回的用量（请求失败但已计费的也算）
+// 计入本次运行与当天的累计。
+func (m *Meter) send(req ai.Request) (string, ai.Usage, error) {
+	if err := m.checkBudget(); err != nil {
+		return "", ai.Usage{}, err
+	}
+
+	reply, u, err := ai.Send(m.Next, req)
+	if m.price != nil {
+		u.Cost = (float64(u.PromptTokens)*m.price.Prompt + float64(u.CompletionTokens)*m.price.Completion) / 1_000_000
+	}
+
+	m.mu.Lock()
+	m.total.Add(u)
+	if err == nil || u.Total() > 0 {
+		m.requests++
+	}
+	m.mu.Unlock()
+
+	if m.ledger != nil && u.Total() > 0 {
+		// 每天的用量只用于预算与统计，写入失败不影响本次结果
+		_ = m.ledger.Add(time.Now(), u)
+	}
+	return reply, u, err
+}
+
+// checkBudget 在本次运行或当天的用量达到上限时返回 ErrBudgetExceeded。
+func (m *Meter) checkBudget() error {
+	b := m.budget
+
+	m.mu.Lock()
+	run := m.total
+	m.mu.Unlock()
+
+	if b.RunTokens > 0 && run.Total() >= b.RunTokens {
+		return fmt.Errorf("%w：本次运行已使用 %d tokens（上限 %d）", ErrBudgetExceeded, run.Total(), b.RunTokens)
+	}
+	if b.RunCost > 0 && run.Cost >= b.RunCost {
+		return fmt.Errorf("%w：本次运行费用 %s%.4f（上限 %s%.4f）", ErrBudgetExceeded, m.currency, run.Cost, m.currency, b.RunCost)
+	}
+
+	if m.ledger == nil || (b.DayTokens <= 0 && b.DayCost <= 0) {
+		return nil
+	}
+	day, err := m.ledger.Day(time.Now())
+	if err != nil {
+		// 读不到当天的用量时无法判断是否超出，宁可不发送请求
+		return fmt.Errorf("%w：读取当天用量失败：%v", ErrBudgetExceeded, err)
+	}
+	if b.DayTokens > 0 && day.Total() >= b.DayTokens {
+		return fmt.Errorf("%w：今天已使用 %d tokens（上限 %d）", ErrBudgetExceeded, day.Total(), b.DayTokens)
+	}
+	if b.DayCost > 0 && day.Cost >= b.DayCost {
+		return fmt.Errorf("%w：今天的费用 %s%.4f（上限 %s%.4f）", ErrBudgetExceeded, m.currency, day.Cost, m.currency, b.DayCost)
+	}
+	return nil
+}
+
+// Totals 返回本次运行累计的用量。
+func (m *Meter) Totals() Totals {
+	warning := ""
+	if m.ledger != nil {
+		warning = m.ledger.Warning()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Totals{
+		Usage:    m.total,
+		Requests: m.requests,
+		Priced:   m.price != nil,
+		Currency: m.currency,
+		Warning:  warning,
+	}
+}